@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Alerts post to ALERT_WEBHOOKS when a draw fails ALERT_FAILURE_THRESHOLD syncs in a row
+// (default 3). Each incident alerts once and a recovery is posted when the draw syncs again.
+// Webhooks are comma separated kind=url pairs, the kind is slack, discord or generic.
+
+type Webhook struct {
+	Kind string
+	URL  string
+}
+
+type DrawFailures struct {
+	Name      string    `json:"name"`
+	Event     string    `json:"event"`
+	Year      int       `json:"year"`
+	Count     int       `json:"count"`
+	Since     time.Time `json:"since"`
+	LastError string    `json:"last_error"`
+	Alerted   bool      `json:"alerted"`
+}
+
+type Alerter struct {
+	Failures  map[string]*DrawFailures `json:"failures"`
+	webhooks  []Webhook
+	threshold int
+	path      string
+}
+
+type AlertPayload struct {
+	Status   string `json:"status"`
+	DrawID   string `json:"draw_id"`
+	Name     string `json:"name"`
+	Event    string `json:"event"`
+	Year     int    `json:"year"`
+	Failures int    `json:"failures"`
+	Error    string `json:"error,omitempty"`
+	Text     string `json:"text"`
+}
+
+func parseWebhooks(s string) []Webhook {
+	webhooks := []Webhook{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kind, url, ok := strings.Cut(entry, "=")
+		if !ok || strings.Contains(kind, "/") {
+			kind, url = "generic", entry
+		}
+
+		switch kind {
+		case "slack", "discord", "generic":
+			webhooks = append(webhooks, Webhook{Kind: kind, URL: url})
+		default:
+			slog.Warn("Unknown alert webhook kind", "kind", kind)
+		}
+	}
+	return webhooks
+}
+
+func loadAlerter() *Alerter {
+	threshold, err := strconv.Atoi(os.Getenv("ALERT_FAILURE_THRESHOLD"))
+	if err != nil || threshold <= 0 {
+		threshold = 3
+	}
+
+	alerter := &Alerter{
+		Failures:  make(map[string]*DrawFailures),
+		webhooks:  parseWebhooks(os.Getenv("ALERT_WEBHOOKS")),
+		threshold: threshold,
+		path:      statePath("alert_state.json"),
+	}
+
+	data, err := os.ReadFile(alerter.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return alerter
+	}
+	if err != nil {
+		slog.Error("Error reading alert state", "error", err)
+		return alerter
+	}
+
+	if err := json.Unmarshal(data, alerter); err != nil {
+		slog.Error("Error decoding alert state", "error", err)
+	}
+	if alerter.Failures == nil {
+		alerter.Failures = make(map[string]*DrawFailures)
+	}
+
+	return alerter
+}
+
+// Counts the draw's consecutive failures, alerting when the threshold is first reached
+// and again when the draw recovers
+func (a *Alerter) record(draw DrawRecord, err error, now time.Time) {
+	if a == nil {
+		return
+	}
+
+	failures, failing := a.Failures[draw.ID]
+
+	if err == nil {
+		if !failing {
+			return
+		}
+		// The incident is kept until the recovery is delivered, so it's retried on the next sync
+		if failures.Alerted && !a.send(AlertPayload{
+			Status:   "recovered",
+			DrawID:   draw.ID,
+			Name:     draw.Name,
+			Event:    draw.Event,
+			Year:     draw.Year,
+			Failures: failures.Count,
+			Text:     fmt.Sprintf("Recovered: %s %s %d is syncing again after %d failures", draw.Name, draw.Event, draw.Year, failures.Count),
+		}) {
+			a.save()
+			return
+		}
+		delete(a.Failures, draw.ID)
+		a.save()
+		return
+	}
+
+	if !failing {
+		failures = &DrawFailures{Since: now}
+		a.Failures[draw.ID] = failures
+	}
+	failures.Name, failures.Event, failures.Year = draw.Name, draw.Event, draw.Year
+	failures.Count++
+	failures.LastError = err.Error()
+
+	// Only marked as alerted once delivered, so an alert that couldn't be sent is retried
+	if failures.Count >= a.threshold && !failures.Alerted {
+		failures.Alerted = a.send(AlertPayload{
+			Status:   "failing",
+			DrawID:   draw.ID,
+			Name:     draw.Name,
+			Event:    draw.Event,
+			Year:     draw.Year,
+			Failures: failures.Count,
+			Error:    failures.LastError,
+			Text: fmt.Sprintf("Failing: %s %s %d has failed %d syncs in a row since %s: %s",
+				draw.Name, draw.Event, draw.Year, failures.Count, failures.Since.Format("2006-01-02 15:04 MST"), failures.LastError),
+		})
+	}
+
+	a.save()
+}
+
+func (a *Alerter) save() {
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		slog.Error("Error encoding alert state", "error", err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+		slog.Error("Error creating state directory", "error", err)
+		return
+	}
+
+	if err := os.WriteFile(a.path, data, 0644); err != nil {
+		slog.Error("Error saving alert state", "error", err)
+	}
+}
+
+// Returns whether at least one webhook accepted the alert. Without webhooks alerts are off.
+func (a *Alerter) send(payload AlertPayload) bool {
+	if len(a.webhooks) == 0 {
+		return false
+	}
+
+	slog.Warn("Sending alert", "status", payload.Status, "failures", payload.Failures, "webhooks", len(a.webhooks))
+
+	delivered := 0
+	for _, webhook := range a.webhooks {
+		if err := postWebhook(webhook, payload); err != nil {
+			slog.Error("Error sending alert", "kind", webhook.Kind, "error", err)
+			continue
+		}
+		delivered++
+	}
+
+	if delivered == 0 {
+		slog.Error("Alert not delivered", "status", payload.Status, "draw_id", payload.DrawID, "webhooks", len(a.webhooks))
+	}
+	return delivered > 0
+}
+
+// Slack and Discord only show their own text field, generic webhooks get the whole payload
+func webhookBody(webhook Webhook, payload AlertPayload) any {
+	switch webhook.Kind {
+	case "slack":
+		return map[string]string{"text": payload.Text}
+	case "discord":
+		return map[string]string{"content": payload.Text}
+	default:
+		return payload
+	}
+}
+
+func postWebhook(webhook Webhook, payload AlertPayload) error {
+	body, err := json.Marshal(webhookBody(webhook, payload))
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	res, err := client.Post(webhook.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWebhooks(t *testing.T) {
+	t.Parallel()
+
+	webhooks := parseWebhooks("slack=https://hooks.slack.com/a, discord=https://discord.com/api/webhooks/b,https://example.com/hook?key=1,teams=https://x")
+	assert.Equal(t, webhooks, []Webhook{
+		{Kind: "slack", URL: "https://hooks.slack.com/a"},
+		{Kind: "discord", URL: "https://discord.com/api/webhooks/b"},
+		{Kind: "generic", URL: "https://example.com/hook?key=1"},
+	})
+	assert.Empty(t, parseWebhooks(""))
+}
+
+func TestAlerter(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string][]map[string]any{}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]any
+		assert.NoError(t, json.Unmarshal(body, &payload))
+
+		mu.Lock()
+		defer mu.Unlock()
+		received[r.URL.Path] = append(received[r.URL.Path], payload)
+	}))
+	defer receiver.Close()
+
+	t.Setenv("STATE_DIR", t.TempDir())
+	t.Setenv("ALERT_FAILURE_THRESHOLD", "3")
+	t.Setenv("ALERT_WEBHOOKS", "slack="+receiver.URL+"/slack,discord="+receiver.URL+"/discord,generic="+receiver.URL+"/generic")
+
+	draw := DrawRecord{ID: "a", Name: "Australian Open", Event: "Men's Singles", Year: 2025}
+	now := time.Date(2025, 1, 20, 14, 0, 0, 0, time.UTC)
+	failure := errors.New("incorrect number of scraped slots, expected 255, received 127")
+
+	alerter := loadAlerter()
+	alerter.record(draw, failure, now)
+	alerter.record(draw, failure, now)
+	assert.Empty(t, received)
+
+	// Third failure in a row crosses the threshold
+	alerter.record(draw, failure, now)
+	assert.Len(t, received["/slack"], 1)
+	assert.Contains(t, received["/slack"][0]["text"], "failed 3 syncs in a row")
+	assert.Contains(t, received["/discord"][0]["content"], "Australian Open")
+	assert.Equal(t, received["/generic"][0]["status"], "failing")
+	assert.Equal(t, received["/generic"][0]["error"], failure.Error())
+
+	// The same incident doesn't alert again, even after a restart
+	alerter = loadAlerter()
+	assert.Equal(t, alerter.Failures["a"].Count, 3)
+	alerter.record(draw, failure, now)
+	assert.Len(t, received["/slack"], 1)
+
+	alerter.record(draw, nil, now)
+	assert.Len(t, received["/slack"], 2)
+	assert.Contains(t, received["/slack"][1]["text"], "Recovered")
+	assert.Equal(t, received["/generic"][1]["status"], "recovered")
+	assert.Empty(t, alerter.Failures)
+
+	// Failures below the threshold recover quietly
+	alerter.record(draw, failure, now)
+	alerter.record(draw, nil, now)
+	assert.Len(t, received["/slack"], 2)
+}
+
+func TestAlerterRetriesUndeliveredAlert(t *testing.T) {
+	down := true
+	delivered := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		delivered++
+	}))
+	defer receiver.Close()
+
+	t.Setenv("STATE_DIR", t.TempDir())
+	t.Setenv("ALERT_FAILURE_THRESHOLD", "1")
+	t.Setenv("ALERT_WEBHOOKS", "generic="+receiver.URL)
+
+	draw := DrawRecord{ID: "a", Name: "Australian Open", Event: "Men's Singles", Year: 2025}
+	now := time.Date(2025, 1, 20, 14, 0, 0, 0, time.UTC)
+	failure := errors.New("invalid event")
+
+	alerter := loadAlerter()
+	alerter.record(draw, failure, now)
+	assert.False(t, alerter.Failures["a"].Alerted)
+
+	// Sent again on the next failure once the webhook is back, then deduplicated
+	down = false
+	alerter.record(draw, failure, now)
+	assert.True(t, alerter.Failures["a"].Alerted)
+	alerter.record(draw, failure, now)
+	assert.Equal(t, delivered, 1)
+
+	// A recovery that isn't delivered keeps the incident until it is
+	down = true
+	alerter.record(draw, nil, now)
+	assert.Contains(t, alerter.Failures, "a")
+	down = false
+	alerter.record(draw, nil, now)
+	assert.Empty(t, alerter.Failures)
+	assert.Equal(t, delivered, 2)
+}
+
+func TestAlerterWithoutWebhooks(t *testing.T) {
+	previous, previousRunID := baseLogger, logRunID
+	t.Cleanup(func() {
+		baseLogger = previous
+		setLogRun(previousRunID)
+	})
+
+	var buf bytes.Buffer
+	baseLogger = newLogger(&buf, "json", slog.LevelInfo)
+	setLogRun("run-1")
+
+	t.Setenv("STATE_DIR", t.TempDir())
+	t.Setenv("ALERT_FAILURE_THRESHOLD", "1")
+	t.Setenv("ALERT_WEBHOOKS", "")
+
+	draw := DrawRecord{ID: "a", Name: "Australian Open", Event: "Men's Singles", Year: 2025}
+	now := time.Date(2025, 1, 20, 14, 0, 0, 0, time.UTC)
+
+	alerter := loadAlerter()
+	alerter.record(draw, errors.New("invalid event"), now)
+	alerter.record(draw, errors.New("invalid event"), now)
+	assert.Equal(t, alerter.Failures["a"].Count, 2)
+	assert.Empty(t, buf.String())
+}
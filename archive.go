@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Every fetched page is archived so past syncs can be replayed. Pages are stored once by
+// content hash in objects/<sha256>.html.gz, and each fetch adds a metadata file in
+// snapshots/<draw id>/<snapshot id>.json pointing at its page. The Pocketbase slots the sync
+// compared the page against are stored the same way in objects/<sha256>.slots.json.gz.
+
+type Snapshot struct {
+	ID        string       `json:"id"`
+	DrawID    string       `json:"draw_id"`
+	URL       string       `json:"url"`
+	FetchedAt time.Time    `json:"fetched_at"`
+	Hash      string       `json:"hash"`
+	Size      int          `json:"size"`
+	Response  ResponseMeta `json:"response"`
+	SlotsHash string       `json:"slots_hash,omitempty"`
+}
+
+type ArchivingScraper struct {
+	Scraper      Scraper
+	DrawID       string
+	Dir          string
+	CurrentSlots SlotSlice
+}
+
+func archiveDir() string {
+	if dir := os.Getenv("ARCHIVE_DIR"); dir != "" {
+		return dir
+	}
+	return statePath("archive")
+}
+
+// Wraps the scraper so pages fetched for the draw are archived with the draw's current slots,
+// unless ARCHIVE_HTML=false
+func archiveScraper(scraper Scraper, draw DrawRecord, currentSlots SlotSlice) Scraper {
+	if os.Getenv("ARCHIVE_HTML") == "false" {
+		return scraper
+	}
+	if archiving, ok := scraper.(*ArchivingScraper); ok {
+		scraper = archiving.Scraper
+	}
+	return &ArchivingScraper{Scraper: scraper, DrawID: draw.ID, Dir: archiveDir(), CurrentSlots: currentSlots}
+}
+
+func (a *ArchivingScraper) scrape(targetURL string) string {
+	html := a.Scraper.scrape(targetURL)
+	if html == "" {
+		return html
+	}
+
+	meta := ResponseMeta{}
+	if responder, ok := a.Scraper.(interface{ lastResponse() ResponseMeta }); ok {
+		meta = responder.lastResponse()
+	}
+
+	if _, err := saveSnapshot(a.Dir, a.DrawID, targetURL, html, a.CurrentSlots, meta, time.Now()); err != nil {
+		slog.Error("Error archiving page", "error", err)
+	}
+
+	return html
+}
+
+func saveSnapshot(dir string, drawID string, targetURL string, html string, currentSlots SlotSlice, meta ResponseMeta, now time.Time) (Snapshot, error) {
+	hash := contentHash([]byte(html))
+
+	snapshot := Snapshot{
+		ID:        fmt.Sprintf("%s-%s", now.UTC().Format("20060102T150405.000Z"), hash[:12]),
+		DrawID:    drawID,
+		URL:       targetURL,
+		FetchedAt: now.UTC(),
+		Hash:      hash,
+		Size:      len(html),
+		Response:  meta,
+	}
+
+	if err := writeObject(dir, hash+".html.gz", []byte(html)); err != nil {
+		return snapshot, err
+	}
+
+	if currentSlots != nil {
+		slots, err := json.Marshal(currentSlots)
+		if err != nil {
+			return snapshot, err
+		}
+		snapshot.SlotsHash = contentHash(slots)
+		if err := writeObject(dir, snapshot.SlotsHash+".slots.json.gz", slots); err != nil {
+			return snapshot, err
+		}
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshotPath := filepath.Join(dir, "snapshots", drawID, snapshot.ID+".json")
+	if err := os.MkdirAll(filepath.Dir(snapshotPath), 0755); err != nil {
+		return snapshot, err
+	}
+	return snapshot, os.WriteFile(snapshotPath, data, 0644)
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Objects are named by content hash, so an existing object is never rewritten
+func writeObject(dir string, name string, data []byte) error {
+	objectPath := filepath.Join(dir, "objects", name)
+	if _, err := os.Stat(objectPath); !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(objectPath, buf.Bytes(), 0644)
+}
+
+func readObject(dir string, name string) ([]byte, error) {
+	file, err := os.Open(filepath.Join(dir, "objects", name))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// Oldest first
+func listSnapshots(dir string, drawID string) ([]Snapshot, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "snapshots", drawID, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []Snapshot{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot := Snapshot{}
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].FetchedAt.Before(snapshots[j].FetchedAt)
+	})
+
+	return snapshots, nil
+}
+
+func readSnapshotHTML(dir string, snapshot Snapshot) (string, error) {
+	data, err := readObject(dir, snapshot.Hash+".html.gz")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Nil when the snapshot was archived without the slots it was synced against
+func readSnapshotSlots(dir string, snapshot Snapshot) (SlotSlice, error) {
+	if snapshot.SlotsHash == "" {
+		return nil, nil
+	}
+
+	data, err := readObject(dir, snapshot.SlotsHash+".slots.json.gz")
+	if err != nil {
+		return nil, err
+	}
+
+	slots := SlotSlice{}
+	if err := json.Unmarshal(data, &slots); err != nil {
+		return nil, err
+	}
+	return slots, nil
+}
+
+// Deletes snapshots older than ARCHIVE_RETENTION_DAYS (default 30), then objects no snapshot uses
+func pruneArchive(dir string, now time.Time) {
+	if os.Getenv("ARCHIVE_HTML") == "false" {
+		return
+	}
+
+	days, err := strconv.Atoi(os.Getenv("ARCHIVE_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		days = 30
+	}
+	cutoff := now.AddDate(0, 0, -days)
+
+	paths, err := filepath.Glob(filepath.Join(dir, "snapshots", "*", "*.json"))
+	if err != nil {
+		slog.Error("Error listing snapshots", "error", err)
+		return
+	}
+
+	used := make(map[string]bool)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			slog.Error("Error reading snapshot", "error", err)
+			return
+		}
+
+		snapshot := Snapshot{}
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			slog.Error("Error decoding snapshot", "path", path, "error", err)
+			continue
+		}
+
+		if snapshot.FetchedAt.Before(cutoff) {
+			err := os.Remove(path)
+			if err == nil {
+				continue
+			}
+			slog.Error("Error deleting snapshot", "error", err)
+		}
+		used[snapshot.Hash+".html.gz"] = true
+		if snapshot.SlotsHash != "" {
+			used[snapshot.SlotsHash+".slots.json.gz"] = true
+		}
+	}
+
+	objects, err := filepath.Glob(filepath.Join(dir, "objects", "*.gz"))
+	if err != nil {
+		slog.Error("Error listing archived objects", "error", err)
+		return
+	}
+
+	for _, object := range objects {
+		if used[filepath.Base(object)] {
+			continue
+		}
+		if err := os.Remove(object); err != nil {
+			slog.Error("Error deleting archived object", "error", err)
+		}
+	}
+}
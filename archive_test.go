@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type staticScraper struct {
+	html string
+}
+
+func (s *staticScraper) scrape(targetURL string) string {
+	return s.html
+}
+
+func TestArchive(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	url := "https://www.atptour.com/en/scores/current/australian-open/580/draws"
+	first := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	a, err := saveSnapshot(dir, "d1", url, "<html>draw</html>", nil, ResponseMeta{StatusCode: 200, Attempts: 1}, first)
+	assert.NoError(err)
+	b, err := saveSnapshot(dir, "d1", url, "<html>draw</html>", allFilled, ResponseMeta{StatusCode: 200, Attempts: 2}, second)
+	assert.NoError(err)
+	c, err := saveSnapshot(dir, "d1", "https://www.atptour.com/en/scores/current/australian-open/580/daily-schedule", "<html>schedule</html>", nil, ResponseMeta{}, second)
+	assert.NoError(err)
+
+	// Identical pages share one object
+	assert.Equal(a.Hash, b.Hash)
+	objects, _ := filepath.Glob(filepath.Join(dir, "objects", "*.html.gz"))
+	assert.Len(objects, 2)
+
+	snapshots, err := listSnapshots(dir, "d1")
+	assert.NoError(err)
+	assert.Len(snapshots, 3)
+	assert.Equal(snapshots[0].ID, a.ID)
+	assert.Equal(snapshots[0].Response.StatusCode, 200)
+
+	latest, ok := findSnapshot(snapshots, "", url)
+	assert.True(ok)
+	assert.Equal(latest.ID, b.ID)
+	byID, ok := findSnapshot(snapshots, c.ID, url)
+	assert.True(ok)
+	assert.Equal(byID.URL, c.URL)
+	_, ok = findSnapshot(snapshots, "missing", url)
+	assert.False(ok)
+
+	html, err := readSnapshotHTML(dir, latest)
+	assert.NoError(err)
+	assert.Equal(html, "<html>draw</html>")
+
+	// Replays compare against the slots the sync saw
+	slots, err := readSnapshotSlots(dir, latest)
+	assert.NoError(err)
+	assert.Equal(slots, allFilled)
+	slots, err = readSnapshotSlots(dir, snapshots[0])
+	assert.NoError(err)
+	assert.Nil(slots)
+
+	// The first snapshot is past retention but its page is still used by the second
+	pruneArchive(dir, second.AddDate(0, 0, 30).Add(-time.Minute))
+	snapshots, _ = listSnapshots(dir, "d1")
+	assert.Len(snapshots, 2)
+	objects, _ = filepath.Glob(filepath.Join(dir, "objects", "*.html.gz"))
+	assert.Len(objects, 2)
+
+	pruneArchive(dir, second.AddDate(0, 0, 30).Add(time.Minute))
+	snapshots, _ = listSnapshots(dir, "d1")
+	assert.Len(snapshots, 0)
+	objects, _ = filepath.Glob(filepath.Join(dir, "objects", "*.gz"))
+	assert.Len(objects, 0)
+}
+
+func TestArchivingScraper(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	scraper := &ArchivingScraper{Scraper: &staticScraper{html: "<html></html>"}, DrawID: "d1", Dir: dir}
+	assert.Equal(t, scraper.scrape("https://www.wtatennis.com/draws"), "<html></html>")
+
+	// Failed fetches aren't archived
+	empty := &ArchivingScraper{Scraper: &staticScraper{}, DrawID: "d2", Dir: dir}
+	assert.Equal(t, empty.scrape("https://www.wtatennis.com/draws"), "")
+
+	entries, _ := os.ReadDir(filepath.Join(dir, "snapshots"))
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Fetch backends get a page through a particular route. ATP_FETCH_BACKEND and WTA_FETCH_BACKEND
+// list the backends to try in order for each provider, e.g. "direct,brightdata,brightdata-secondary":
+//   - direct: plain HTTP request
+//   - proxy: any HTTP proxy at HTTP_PROXY_URL
+//   - brightdata: Bright Data Web Unlocker at PROXY_URL (default)
+//   - brightdata-secondary: a second Bright Data zone at PROXY_URL_SECONDARY
+//   - browser: headless browser service at BROWSER_SERVICE_URL
+
+type FetchBackend interface {
+	name() string
+	fetch(targetURL string) (string, ResponseMeta, error)
+}
+
+// Waiting for rendering can take a while so backends use a longer timeout
+const fetchTimeout = 600 * time.Second
+
+func providerFor(targetURL string) string {
+	parsed, err := url.Parse(targetURL)
+	if err != nil {
+		return ""
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	switch {
+	case host == "atptour.com" || strings.HasSuffix(host, ".atptour.com"):
+		return "atp"
+	case host == "wtatennis.com" || strings.HasSuffix(host, ".wtatennis.com"):
+		return "wta"
+	default:
+		return ""
+	}
+}
+
+// ATP or WTA draw page, ignoring a trailing slash, query string or fragment
+func isDrawPage(targetURL string) bool {
+	parsed, err := url.Parse(targetURL)
+	if err != nil || providerFor(targetURL) == "" {
+		return false
+	}
+	return strings.HasSuffix(strings.TrimRight(parsed.Path, "/"), "/draws")
+}
+
+// Element that shows the page has rendered, for backends that can wait for one.
+// WTA draws only have tie-break scores once scores and winners have been rendered,
+// other WTA pages like the order of play don't have them.
+func waitForSelector(targetURL string) string {
+	if providerFor(targetURL) == "wta" && isDrawPage(targetURL) {
+		return ".match-table__tie-break"
+	}
+	return ""
+}
+
+func newFetchBackend(kind string) (FetchBackend, error) {
+	switch kind {
+	case "direct":
+		return &DirectBackend{}, nil
+	case "proxy":
+		return newProxyBackend("proxy", os.Getenv("HTTP_PROXY_URL"), os.Getenv("HTTP_PROXY_INSECURE") == "true")
+	case "", "brightdata":
+		return newBrightDataBackend("brightdata", os.Getenv("PROXY_URL"))
+	case "brightdata-secondary":
+		return newBrightDataBackend("brightdata-secondary", os.Getenv("PROXY_URL_SECONDARY"))
+	case "browser":
+		endpoint := os.Getenv("BROWSER_SERVICE_URL")
+		if endpoint == "" {
+			return nil, fmt.Errorf("BROWSER_SERVICE_URL is not set")
+		}
+		return &BrowserBackend{Endpoint: endpoint}, nil
+	default:
+		return nil, fmt.Errorf("unknown fetch backend %q", kind)
+	}
+}
+
+// Fallback chain for the provider of the URL, from <PROVIDER>_FETCH_BACKEND.
+// Backends that aren't configured are left out of the chain.
+func fetchBackendsFor(targetURL string) ([]FetchBackend, error) {
+	provider := providerFor(targetURL)
+
+	backends := []FetchBackend{}
+	for _, kind := range strings.Split(os.Getenv(strings.ToUpper(provider)+"_FETCH_BACKEND"), ",") {
+		backend, err := newFetchBackend(trim(kind))
+		if err != nil {
+			slog.Error("Skipping fetch backend", "error", err)
+			continue
+		}
+		backends = append(backends, backend)
+	}
+
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("no fetch backends configured for %q", provider)
+	}
+	return backends, nil
+}
+
+// Provider specific check that a draw page has rendered, bot challenges and
+// unrendered shells fail it. Other pages only need a body.
+func looksRendered(targetURL string, html string) bool {
+	if !isDrawPage(targetURL) {
+		return strings.Contains(html, "<body")
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return false
+	}
+
+	// Same selectors as the readiness check, see pageAssertions
+	for _, selector := range pageAssertions[providerFor(targetURL)].Selectors {
+		if doc.Find(selector).Length() == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func doFetch(client *http.Client, req *http.Request) (string, ResponseMeta, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", ResponseMeta{}, err
+	}
+	defer resp.Body.Close()
+
+	meta := ResponseMeta{StatusCode: resp.StatusCode, ContentType: resp.Header.Get("Content-Type")}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", meta, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", meta, fmt.Errorf("status %s", resp.Status)
+	}
+
+	return string(body), meta, nil
+}
+
+type DirectBackend struct{}
+
+func (b *DirectBackend) name() string {
+	return "direct"
+}
+
+func (b *DirectBackend) fetch(targetURL string) (string, ResponseMeta, error) {
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return "", ResponseMeta{}, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; tennis-bracket-scripts)")
+
+	return doFetch(&http.Client{Timeout: fetchTimeout}, req)
+}
+
+type ProxyBackend struct {
+	Name     string
+	ProxyURL *url.URL
+	Insecure bool
+}
+
+func newProxyBackend(name string, proxy string, insecure bool) (*ProxyBackend, error) {
+	if proxy == "" {
+		return nil, fmt.Errorf("proxy URL for %s is not set", name)
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL for %s: %w", name, err)
+	}
+	return &ProxyBackend{Name: name, ProxyURL: proxyURL, Insecure: insecure}, nil
+}
+
+func (b *ProxyBackend) name() string {
+	return b.Name
+}
+
+func (b *ProxyBackend) client() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyURL(b.ProxyURL),
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: b.Insecure},
+		},
+		Timeout: fetchTimeout,
+	}
+}
+
+func (b *ProxyBackend) fetch(targetURL string) (string, ResponseMeta, error) {
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return "", ResponseMeta{}, err
+	}
+	return doFetch(b.client(), req)
+}
+
+// Bright Data re-signs responses with its own certificate, so TLS verification is off
+type BrightDataBackend struct {
+	ProxyBackend
+}
+
+func newBrightDataBackend(name string, proxy string) (*BrightDataBackend, error) {
+	backend, err := newProxyBackend(name, proxy, true)
+	if err != nil {
+		return nil, err
+	}
+	return &BrightDataBackend{ProxyBackend: *backend}, nil
+}
+
+func (b *BrightDataBackend) fetch(targetURL string) (string, ResponseMeta, error) {
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return "", ResponseMeta{}, err
+	}
+
+	if selector := waitForSelector(targetURL); selector != "" {
+		req.Header.Set("x-unblock-expect", fmt.Sprintf(`{"element": "%s"}`, selector))
+	}
+
+	return doFetch(b.client(), req)
+}
+
+// A local service that renders the page in a headless browser and returns the HTML
+type BrowserBackend struct {
+	Endpoint string
+}
+
+func (b *BrowserBackend) name() string {
+	return "browser"
+}
+
+func (b *BrowserBackend) fetch(targetURL string) (string, ResponseMeta, error) {
+	payload, err := json.Marshal(struct {
+		URL       string `json:"url"`
+		WaitFor   string `json:"wait_for,omitempty"`
+		TimeoutMs int64  `json:"timeout_ms"`
+	}{targetURL, waitForSelector(targetURL), fetchTimeout.Milliseconds()})
+	if err != nil {
+		return "", ResponseMeta{}, err
+	}
+
+	req, err := http.NewRequest("POST", b.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", ResponseMeta{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return doFetch(&http.Client{Timeout: fetchTimeout}, req)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Correction mode lets the script clear or replace a slot that already has a name.
+// A destructive change is only emitted once the same value has been scraped
+// for CORRECTION_CONFIRMATIONS consecutive runs, so a one-off site glitch can't wipe data.
+
+type PendingCorrection struct {
+	Proposed  string `json:"proposed"`
+	Count     int    `json:"count"`
+	FirstSeen string `json:"first_seen"`
+}
+
+type CorrectionTracker struct {
+	Threshold int                          `json:"-"`
+	Pending   map[string]PendingCorrection `json:"pending"`
+	path      string
+}
+
+type Correction struct {
+	DrawID        string `json:"draw_id"`
+	Round         int    `json:"round"`
+	Position      int    `json:"position"`
+	Before        Slot   `json:"before"`
+	After         Slot   `json:"after"`
+	Confirmations int    `json:"confirmations"`
+}
+
+type CorrectionSlice []Correction
+
+func (cs *CorrectionSlice) add(c Correction) {
+	*cs = append(*cs, c)
+}
+
+func newCorrectionTracker(threshold int, path string) *CorrectionTracker {
+	return &CorrectionTracker{
+		Threshold: threshold,
+		Pending:   make(map[string]PendingCorrection),
+		path:      path,
+	}
+}
+
+// Returns nil when correction mode is disabled
+func loadCorrectionTracker() *CorrectionTracker {
+	threshold, err := strconv.Atoi(os.Getenv("CORRECTION_CONFIRMATIONS"))
+	if err != nil || threshold <= 0 {
+		return nil
+	}
+
+	tracker := newCorrectionTracker(threshold, statePath("corrections_pending.json"))
+
+	data, err := os.ReadFile(tracker.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tracker
+	}
+	if err != nil {
+		slog.Error("Error reading pending corrections", "error", err)
+		return tracker
+	}
+
+	if err := json.Unmarshal(data, tracker); err != nil {
+		slog.Error("Error decoding pending corrections", "error", err)
+	}
+	if tracker.Pending == nil {
+		tracker.Pending = make(map[string]PendingCorrection)
+	}
+
+	return tracker
+}
+
+func (ct *CorrectionTracker) save() {
+	if ct == nil || ct.path == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(ct, "", "  ")
+	if err != nil {
+		slog.Error("Error encoding pending corrections", "error", err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ct.path), 0755); err != nil {
+		slog.Error("Error creating state directory", "error", err)
+		return
+	}
+
+	if err := os.WriteFile(ct.path, data, 0644); err != nil {
+		slog.Error("Error saving pending corrections", "error", err)
+	}
+}
+
+func correctionKey(drawID string, round int, position int) string {
+	return fmt.Sprintf("%s/%d/%d", drawID, round, position)
+}
+
+// Counts another scrape proposing the value for the key.
+// Returns true once the threshold is reached. The pending entry is kept until
+// the correction is written, see resolve.
+func (ct *CorrectionTracker) confirm(key string, proposed string) (bool, int) {
+	pending, ok := ct.Pending[key]
+	if !ok || pending.Proposed != proposed {
+		pending = PendingCorrection{
+			Proposed:  proposed,
+			FirstSeen: time.Now().UTC().Format(time.RFC3339),
+		}
+	}
+	pending.Count++
+	ct.Pending[key] = pending
+
+	return pending.Count >= ct.Threshold, pending.Count
+}
+
+// Forgets the pending entries of corrections whose slot was written, a failed
+// write stays confirmed and is retried on the next run
+func (ct *CorrectionTracker) resolve(corrections CorrectionSlice, written map[string]bool) {
+	if ct == nil {
+		return
+	}
+	for _, correction := range corrections {
+		if written[correction.After.ID] {
+			ct.reset(correctionKey(correction.DrawID, correction.Round, correction.Position))
+		}
+	}
+}
+
+// Whether any correction or set deletion for the draw is still waiting for confirmation
+func (ct *CorrectionTracker) hasPending(drawID string) bool {
+	if ct == nil {
+		return false
+	}
+	for key := range ct.Pending {
+		if strings.HasPrefix(key, drawID+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Scrape agrees with the current data, so any pending correction is no longer consecutive
+func (ct *CorrectionTracker) reset(key string) {
+	delete(ct.Pending, key)
+}
+
+func recordCorrection(c Correction, status string) {
+	entry := struct {
+		Timestamp string `json:"timestamp"`
+		Status    string `json:"status"`
+		Correction
+	}{
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		Status:     status,
+		Correction: c,
+	}
+
+	if err := appendJSONLine(statePath("corrections.jsonl"), entry); err != nil {
+		slog.Error("Error recording correction", "error", err)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// The daemon keeps running and scrapes each draw on its own schedule instead of a fixed cron
+// cadence. The next run for each draw is saved, so a restart doesn't scrape everything at once.
+
+const (
+	matchHoursStart = 10
+	matchHoursEnd   = 24
+	// Longest sleep between cycles, so newly active draws are picked up
+	daemonMaxSleep = 5 * time.Minute
+)
+
+type DaemonSchedule struct {
+	NextRun map[string]time.Time `json:"next_run"`
+	path    string
+}
+
+func loadDaemonSchedule(path string) *DaemonSchedule {
+	schedule := &DaemonSchedule{NextRun: make(map[string]time.Time), path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return schedule
+	}
+	if err != nil {
+		slog.Error("Error reading daemon schedule", "error", err)
+		return schedule
+	}
+
+	if err := json.Unmarshal(data, schedule); err != nil {
+		slog.Error("Error decoding daemon schedule", "error", err)
+	}
+	if schedule.NextRun == nil {
+		schedule.NextRun = make(map[string]time.Time)
+	}
+
+	return schedule
+}
+
+func (ds *DaemonSchedule) save() {
+	data, err := json.MarshalIndent(ds, "", "  ")
+	if err != nil {
+		slog.Error("Error encoding daemon schedule", "error", err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ds.path), 0755); err != nil {
+		slog.Error("Error creating state directory", "error", err)
+		return
+	}
+
+	if err := os.WriteFile(ds.path, data, 0644); err != nil {
+		slog.Error("Error saving daemon schedule", "error", err)
+	}
+}
+
+// Draws without a saved next run are due straight away
+func (ds *DaemonSchedule) due(drawID string, now time.Time) bool {
+	next, ok := ds.NextRun[drawID]
+	return !ok || !now.Before(next)
+}
+
+// Match hours are in the draw's timezone
+func inMatchHours(draw DrawRecord, now time.Time) bool {
+	hour := now.In(drawLocation(draw)).Hour()
+	return hour >= matchHoursStart && hour < matchHoursEnd
+}
+
+// Zero means the draw doesn't need scraping again
+func pollInterval(draw DrawRecord, now time.Time) time.Duration {
+	switch draw.State {
+	case DrawStateDraft, DrawStateArchived:
+		return 0
+	case DrawStateCompleted:
+		// Not scraped, only checked until it's archived
+		return 6 * time.Hour
+	case DrawStateInProgress:
+		if inMatchHours(draw, now) {
+			return 5 * time.Minute
+		}
+		return time.Hour
+	case DrawStatePublished, DrawStatePredictionsOpen:
+		return 30 * time.Minute
+	default:
+		return 6 * time.Hour
+	}
+}
+
+// Spreads runs by up to 20% either way so draws don't line up
+func jitter(interval time.Duration, r *rand.Rand) time.Duration {
+	return interval + time.Duration((r.Float64()*0.4-0.2)*float64(interval))
+}
+
+func runDaemon() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	schedule := loadDaemonSchedule(statePath("daemon_schedule.json"))
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	scraper := &RealScraper{}
+
+	alerter := loadAlerter()
+	health := newHealthState(time.Now())
+	server := startServer(health)
+	defer server.Close()
+
+	slog.Info("Daemon started", "addr", server.Addr)
+
+	for {
+		runDaemonCycle(schedule, scraper, r, health, alerter)
+
+		sleep := daemonMaxSleep
+		now := time.Now()
+		for _, next := range schedule.NextRun {
+			sleep = min(sleep, max(next.Sub(now), time.Second))
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Daemon stopped")
+			return
+		case <-time.After(sleep):
+		}
+	}
+}
+
+// Logs in every cycle so the token doesn't expire
+func runDaemonCycle(schedule *DaemonSchedule, scraper Scraper, r *rand.Rand, health *HealthState, alerter *Alerter) {
+	token := login()
+	health.setToken(token)
+
+	draws, err := getDraws(token)
+	if err != nil {
+		slog.Error("Error getting draws, retrying next cycle", "error", err)
+		return
+	}
+	health.setDraws(draws)
+
+	active := make(map[string]bool)
+	for _, draw := range draws {
+		active[draw.ID] = true
+	}
+	for drawID := range schedule.NextRun {
+		if !active[drawID] {
+			delete(schedule.NextRun, drawID)
+		}
+	}
+
+	tracker := loadCorrectionTracker()
+	defer tracker.save()
+	journal := newJournal(newRunID(), token)
+	setLogRun(journal.RunID)
+	hashes := loadPageHashes()
+
+	for _, draw := range draws {
+		if !schedule.due(draw.ID, time.Now()) {
+			continue
+		}
+
+		draw, err := syncDraw(draw, scraper, token, tracker, hashes, journal, nil)
+		health.recordSync(draw, err, time.Now())
+		alerter.record(draw, err, time.Now())
+
+		now := time.Now()
+		if interval := pollInterval(draw, now); interval > 0 {
+			schedule.NextRun[draw.ID] = now.Add(jitter(interval, r))
+		} else {
+			delete(schedule.NextRun, draw.ID)
+		}
+		schedule.save()
+	}
+
+	pruneArchive(archiveDir(), time.Now())
+}
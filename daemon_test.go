@@ -0,0 +1,54 @@
+package main
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPollInterval(t *testing.T) {
+	t.Parallel()
+
+	afternoon := time.Date(2025, 1, 20, 14, 0, 0, 0, time.UTC)
+	night := time.Date(2025, 1, 20, 3, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, pollInterval(DrawRecord{State: DrawStateInProgress}, afternoon), 5*time.Minute)
+	assert.Equal(t, pollInterval(DrawRecord{State: DrawStateInProgress}, night), time.Hour)
+	assert.Equal(t, pollInterval(DrawRecord{State: DrawStatePredictionsOpen}, night), 30*time.Minute)
+	assert.Equal(t, pollInterval(DrawRecord{State: DrawStateScheduled}, afternoon), 6*time.Hour)
+	assert.Equal(t, pollInterval(DrawRecord{State: DrawStateCompleted}, afternoon), 6*time.Hour)
+	assert.Equal(t, pollInterval(DrawRecord{State: DrawStateArchived}, afternoon), time.Duration(0))
+}
+
+func TestJitter(t *testing.T) {
+	t.Parallel()
+
+	r := rand.New(rand.NewSource(1))
+	for range 100 {
+		d := jitter(10*time.Minute, r)
+		assert.GreaterOrEqual(t, d, 8*time.Minute)
+		assert.LessOrEqual(t, d, 12*time.Minute)
+	}
+}
+
+func TestDaemonSchedule(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "daemon_schedule.json")
+	now := time.Date(2025, 1, 20, 14, 0, 0, 0, time.UTC)
+
+	schedule := loadDaemonSchedule(path)
+	assert.True(t, schedule.due("a", now))
+
+	schedule.NextRun["a"] = now.Add(time.Hour)
+	schedule.save()
+
+	// Reloaded after a restart, the draw waits for its saved next run
+	reloaded := loadDaemonSchedule(path)
+	assert.False(t, reloaded.due("a", now))
+	assert.True(t, reloaded.due("a", now.Add(time.Hour)))
+	assert.True(t, reloaded.due("b", now))
+}
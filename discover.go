@@ -0,0 +1,299 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Discover scrapes the ATP and WTA tournament calendars and creates draft draw records for
+// upcoming events. Drafts are never scraped, an admin approves one by clearing its state
+// or setting it to scheduled after checking the URL, size and dates.
+
+type DiscoveredEvent struct {
+	Name      string
+	Event     string
+	Tier      string
+	StartDate time.Time
+	EndDate   time.Time
+	Url       string
+	Size      int
+}
+
+const (
+	atpCalendarURL = "https://www.atptour.com/en/tournaments"
+	wtaCalendarURL = "https://www.wtatennis.com/tournaments"
+)
+
+// Bracket sizes, a 96 player draw is shown as 128 with byes
+var defaultDrawSizes = map[string]int{
+	"grand slam":   128,
+	"masters 1000": 128,
+	"wta 1000":     128,
+}
+
+func runDiscover(args []string) {
+	flags := flag.NewFlagSet("discover", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "list discovered events without creating draws")
+	flags.Parse(args)
+
+	tiers := discoverTiers()
+	scraper := &RealScraper{}
+	now := time.Now()
+
+	events := []DiscoveredEvent{}
+	events = append(events, parseATPCalendar(scraper.scrape(atpCalendarURL))...)
+	events = append(events, parseWTACalendar(scraper.scrape(wtaCalendarURL))...)
+	events = filterEvents(events, tiers, now)
+
+	if len(events) == 0 {
+		slog.Info("No upcoming events found")
+		return
+	}
+
+	token := ""
+	if !*dryRun {
+		token = login()
+	}
+	journal := newJournal(newRunID(), token)
+	setLogRun(journal.RunID)
+	existing := make(map[int]map[string]bool)
+
+	for _, event := range events {
+		year := event.StartDate.Year()
+		if *dryRun {
+			slog.Info("Would create draft", "name", event.Name, "event", event.Event, "year", year, "tier", event.Tier, "url", event.Url, "size", event.Size)
+			continue
+		}
+
+		if _, ok := existing[year]; !ok {
+			draws := getDrawsByYear(year, token)
+			if draws == nil {
+				slog.Warn("Skipping event, couldn't check for existing draws", "name", event.Name, "event", event.Event, "year", year)
+				continue
+			}
+
+			names := make(map[string]bool)
+			for _, draw := range draws {
+				names[draw.Name+"|"+draw.Event] = true
+			}
+			existing[year] = names
+		}
+
+		if existing[year][event.Name+"|"+event.Event] {
+			continue
+		}
+
+		requestData := draftRequest(event, year)
+
+		id, err := writeRecord("POST", "draw", "", requestData, token)
+		if err != nil {
+			slog.Error("Error creating draft draw", "error", err)
+			continue
+		}
+		existing[year][event.Name+"|"+event.Event] = true
+
+		journal.record("create", "draw", id, toRawJSON(nil), toRawJSON(requestData))
+		slog.Info("Created draft", "draw_id", id, "name", event.Name, "event", event.Event, "year", year, "url", event.Url)
+	}
+}
+
+// Predictions close at midnight at the tournament on the start date, stored as the exact UTC time
+func draftRequest(event DiscoveredEvent, year int) CreateDrawReq {
+	timezone := tournamentTimezone(event.Name)
+	start := event.StartDate.Format("2006-01-02 15:04:05.000Z")
+
+	predictionClose, err := drawDate(start, drawLocation(DrawRecord{Name: event.Name, Timezone: timezone}))
+	if err != nil {
+		predictionClose = event.StartDate
+	}
+
+	return CreateDrawReq{
+		Name:             event.Name,
+		Event:            event.Event,
+		Year:             year,
+		Url:              event.Url,
+		Start_Date:       start,
+		End_Date:         event.EndDate.Format("2006-01-02 15:04:05.000Z"),
+		Prediction_Close: predictionClose.UTC().Format("2006-01-02 15:04:05.000Z"),
+		Size:             event.Size,
+		State:            DrawStateDraft,
+		Timezone:         timezone,
+	}
+}
+
+func discoverTiers() []string {
+	tiers := os.Getenv("DISCOVER_TIERS")
+	if tiers == "" {
+		tiers = "Grand Slam,Masters 1000,WTA 1000"
+	}
+
+	result := []string{}
+	for _, tier := range strings.Split(tiers, ",") {
+		result = append(result, strings.ToLower(trim(tier)))
+	}
+	return result
+}
+
+func matchTier(tier string, tiers []string) string {
+	tier = strings.ToLower(tier)
+	for _, t := range tiers {
+		if strings.Contains(tier, t) {
+			return t
+		}
+	}
+	return ""
+}
+
+// Keeps events in the configured tiers that haven't finished yet
+func filterEvents(events []DiscoveredEvent, tiers []string, now time.Time) []DiscoveredEvent {
+	result := []DiscoveredEvent{}
+	for _, event := range events {
+		tier := matchTier(event.Tier, tiers)
+		if tier == "" || event.EndDate.Before(now) || event.Url == "" {
+			continue
+		}
+		if event.Size == 0 {
+			event.Size = defaultDrawSizes[tier]
+		}
+		event.Size = bracketSize(event.Size)
+		result = append(result, event)
+	}
+	return result
+}
+
+// Player counts like 56 or 96 are rounded up to the bracket the sites render, with byes
+func bracketSize(players int) int {
+	if players <= 0 {
+		return 0
+	}
+	size := 1
+	for size < players {
+		size *= 2
+	}
+	return size
+}
+
+var (
+	atpDateRange = regexp.MustCompile(`^(\d{1,2})(?:\s+([A-Za-z]+))?\s*-\s*(\d{1,2})\s+([A-Za-z]+),\s*(\d{4})$`)
+	atpProfile   = regexp.MustCompile(`^/en/tournaments/([^/]+)/(\d+)/`)
+)
+
+// ATP dates look like "12 - 26 January, 2025" or "26 May - 8 June, 2025"
+func parseATPDates(s string) (time.Time, time.Time, error) {
+	match := atpDateRange.FindStringSubmatch(trim(s))
+	if match == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid ATP dates: %q", s)
+	}
+
+	startMonth := match[2]
+	if startMonth == "" {
+		startMonth = match[4]
+	}
+
+	start, err := time.Parse("2 January 2006", fmt.Sprintf("%s %s %s", match[1], startMonth, match[5]))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, err := time.Parse("2 January 2006", fmt.Sprintf("%s %s %s", match[3], match[4], match[5]))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	// Events over new year, e.g. "29 December - 5 January, 2025"
+	if end.Before(start) {
+		start = start.AddDate(-1, 0, 0)
+	}
+
+	return start, end, nil
+}
+
+func parseATPCalendar(html string) []DiscoveredEvent {
+	events := []DiscoveredEvent{}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		slog.Error("Error parsing calendar", "error", err)
+		return events
+	}
+
+	doc.Find(".tournament-info").Each(func(_ int, info *goquery.Selection) {
+		name := trim(info.Find(".name").First().Text())
+		tier, _ := info.Find(".events_banner").Attr("alt")
+		href, _ := info.Find(".tournament__profile").Attr("href")
+
+		start, end, err := parseATPDates(info.Find(".Date").First().Text())
+		if err != nil {
+			slog.Warn("Invalid ATP calendar dates", "name", name, "error", err)
+			return
+		}
+
+		drawURL := ""
+		if match := atpProfile.FindStringSubmatch(href); match != nil {
+			drawURL = fmt.Sprintf("https://www.atptour.com/en/scores/current/%s/%s/draws", match[1], match[2])
+		}
+
+		size, _ := strconv.Atoi(info.AttrOr("data-draw-size", ""))
+
+		events = append(events, DiscoveredEvent{
+			Name:      name,
+			Event:     "Men's Singles",
+			Tier:      tier,
+			StartDate: start,
+			EndDate:   end,
+			Url:       drawURL,
+			Size:      size,
+		})
+	})
+
+	return events
+}
+
+func parseWTACalendar(html string) []DiscoveredEvent {
+	events := []DiscoveredEvent{}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		slog.Error("Error parsing calendar", "error", err)
+		return events
+	}
+
+	doc.Find(".tournament-thumbnail").Each(func(_ int, thumbnail *goquery.Selection) {
+		name := trim(thumbnail.Find(".tournament-thumbnail__title").First().Text())
+		tier := trim(thumbnail.Find(".tournament-thumbnail__tag").First().Text())
+		href, _ := thumbnail.Find(".tournament-thumbnail__link").Attr("href")
+
+		start, startErr := time.Parse("2006-01-02", thumbnail.AttrOr("data-start-date", ""))
+		end, endErr := time.Parse("2006-01-02", thumbnail.AttrOr("data-end-date", ""))
+		if startErr != nil || endErr != nil {
+			slog.Warn("Invalid WTA calendar dates", "name", name)
+			return
+		}
+
+		drawURL := ""
+		if href != "" {
+			drawURL = "https://www.wtatennis.com" + strings.TrimSuffix(href, "/") + "/draws"
+		}
+
+		size, _ := strconv.Atoi(thumbnail.AttrOr("data-draw-size", ""))
+
+		events = append(events, DiscoveredEvent{
+			Name:      name,
+			Event:     "Women's Singles",
+			Tier:      tier,
+			StartDate: start,
+			EndDate:   end,
+			Url:       drawURL,
+			Size:      size,
+		})
+	})
+
+	return events
+}
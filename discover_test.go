@@ -0,0 +1,143 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const atpCalendarHTML = `
+<ul class="events">
+	<li><div class="tournament-info">
+		<a class="tournament__profile" href="/en/tournaments/australian-open/580/overview"><img class="events_banner" alt="Grand Slam"></a>
+		<span class="name">Australian Open</span>
+		<span class="Date">12 - 26 January, 2025</span>
+	</div></li>
+	<li><div class="tournament-info" data-draw-size="56">
+		<a class="tournament__profile" href="/en/tournaments/monte-carlo/410/overview"><img class="events_banner" alt="ATP Masters 1000"></a>
+		<span class="name">Monte-Carlo Masters</span>
+		<span class="Date">6 - 13 April, 2025</span>
+	</div></li>
+	<li><div class="tournament-info">
+		<a class="tournament__profile" href="/en/tournaments/roland-garros/520/overview"><img class="events_banner" alt="Grand Slam"></a>
+		<span class="name">Roland Garros</span>
+		<span class="Date">25 May - 8 June, 2025</span>
+	</div></li>
+	<li><div class="tournament-info">
+		<a class="tournament__profile" href="/en/tournaments/adelaide/8998/overview"><img class="events_banner" alt="ATP 250"></a>
+		<span class="name">Adelaide International</span>
+		<span class="Date">6 - 11 January, 2025</span>
+	</div></li>
+</ul>`
+
+const wtaCalendarHTML = `
+<ul>
+	<li class="tournament-thumbnail" data-start-date="2025-03-05" data-end-date="2025-03-16">
+		<a class="tournament-thumbnail__link" href="/tournaments/609/indian-wells/2025/"></a>
+		<span class="tournament-thumbnail__title">Indian Wells</span>
+		<span class="tournament-thumbnail__tag">WTA 1000</span>
+	</li>
+	<li class="tournament-thumbnail" data-start-date="2025-02-24" data-end-date="2025-03-02">
+		<a class="tournament-thumbnail__link" href="/tournaments/2082/austin/2025"></a>
+		<span class="tournament-thumbnail__title">Austin</span>
+		<span class="tournament-thumbnail__tag">WTA 250</span>
+	</li>
+</ul>`
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseATPCalendar(t *testing.T) {
+	t.Parallel()
+
+	events := parseATPCalendar(atpCalendarHTML)
+	assert := assert.New(t)
+	assert.Len(events, 4)
+	assert.Equal(events[0], DiscoveredEvent{
+		Name:      "Australian Open",
+		Event:     "Men's Singles",
+		Tier:      "Grand Slam",
+		StartDate: date(2025, time.January, 12),
+		EndDate:   date(2025, time.January, 26),
+		Url:       "https://www.atptour.com/en/scores/current/australian-open/580/draws",
+	})
+	assert.Equal(events[1].Size, 56)
+	assert.Equal(events[2].StartDate, date(2025, time.May, 25))
+	assert.Equal(events[2].EndDate, date(2025, time.June, 8))
+}
+
+func TestParseWTACalendar(t *testing.T) {
+	t.Parallel()
+
+	events := parseWTACalendar(wtaCalendarHTML)
+	assert := assert.New(t)
+	assert.Len(events, 2)
+	assert.Equal(events[0], DiscoveredEvent{
+		Name:      "Indian Wells",
+		Event:     "Women's Singles",
+		Tier:      "WTA 1000",
+		StartDate: date(2025, time.March, 5),
+		EndDate:   date(2025, time.March, 16),
+		Url:       "https://www.wtatennis.com/tournaments/609/indian-wells/2025/draws",
+	})
+}
+
+func TestFilterEvents(t *testing.T) {
+	t.Parallel()
+
+	events := append(parseATPCalendar(atpCalendarHTML), parseWTACalendar(wtaCalendarHTML)...)
+	filtered := filterEvents(events, []string{"grand slam", "masters 1000", "wta 1000"}, date(2025, time.February, 1))
+
+	names := []string{}
+	sizes := []int{}
+	for _, event := range filtered {
+		names = append(names, event.Name)
+		sizes = append(sizes, event.Size)
+	}
+
+	assert := assert.New(t)
+	assert.Equal(names, []string{"Monte-Carlo Masters", "Roland Garros", "Indian Wells"})
+	assert.Equal(sizes, []int{64, 128, 128})
+}
+
+func TestBracketSize(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, bracketSize(28), 32)
+	assert.Equal(t, bracketSize(56), 64)
+	assert.Equal(t, bracketSize(96), 128)
+	assert.Equal(t, bracketSize(128), 128)
+	assert.Equal(t, bracketSize(0), 0)
+}
+
+func TestParseATPDates(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	start, end, err := parseATPDates("29 December - 5 January, 2025")
+	assert.NoError(err)
+	assert.Equal(start, date(2024, time.December, 29))
+	assert.Equal(end, date(2025, time.January, 5))
+
+	_, _, err = parseATPDates("TBC")
+	assert.Error(err)
+}
+
+func TestDraftRequest(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	start := time.Date(2025, 1, 12, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 1, 26, 0, 0, 0, 0, time.UTC)
+
+	draft := draftRequest(DiscoveredEvent{Name: "Australian Open", Event: "Men's Singles", StartDate: start, EndDate: end}, 2025)
+	assert.Equal(draft.Timezone, "Australia/Melbourne")
+	assert.Equal(draft.Start_Date, "2025-01-12 00:00:00.000Z")
+	assert.Equal(draft.Prediction_Close, "2025-01-11 13:00:00.000Z")
+
+	draft = draftRequest(DiscoveredEvent{Name: "Unknown Open", Event: "Men's Singles", StartDate: start, EndDate: end}, 2025)
+	assert.Equal(draft.Timezone, "")
+	assert.Equal(draft.Prediction_Close, "2025-01-12 00:00:00.000Z")
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Health of the daemon for the droplet's supervisor. /healthz fails when an active draw is
+// stale, i.e. in progress during match hours without a successful sync for
+// HEALTH_STALE_MINUTES (default 30). /readyz also checks Pocketbase and the auth token.
+
+type DrawHealth struct {
+	DrawID      string     `json:"draw_id"`
+	Name        string     `json:"name"`
+	Event       string     `json:"event"`
+	State       string     `json:"state"`
+	LastSuccess *time.Time `json:"last_success"`
+	LastError   string     `json:"last_error,omitempty"`
+	Stale       bool       `json:"stale"`
+}
+
+type HealthReport struct {
+	Status     string       `json:"status"`
+	Pocketbase string       `json:"pocketbase,omitempty"`
+	Token      string       `json:"token,omitempty"`
+	Draws      []DrawHealth `json:"draws"`
+}
+
+type HealthState struct {
+	mu          sync.Mutex
+	started     time.Time
+	token       string
+	draws       map[string]DrawRecord
+	lastSuccess map[string]time.Time
+	lastError   map[string]string
+	tokenCheck  TokenCheck
+	// Pocketbase checks, replaced in tests
+	checkPocketbase func() error
+	checkToken      func(token string) error
+}
+
+// Latest token check, auth-refresh issues a new token so it isn't called on every probe
+type TokenCheck struct {
+	token string
+	err   error
+	at    time.Time
+}
+
+const tokenCheckInterval = 5 * time.Minute
+
+func newHealthState(now time.Time) *HealthState {
+	return &HealthState{
+		started:         now,
+		draws:           make(map[string]DrawRecord),
+		lastSuccess:     make(map[string]time.Time),
+		lastError:       make(map[string]string),
+		checkPocketbase: checkPocketbaseHealth,
+		checkToken:      checkTokenValid,
+	}
+}
+
+func (h *HealthState) setToken(token string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.token = token
+}
+
+// Active draws from the latest cycle, draws that are no longer active are dropped
+func (h *HealthState) setDraws(draws []DrawRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.draws = make(map[string]DrawRecord)
+	for _, draw := range draws {
+		h.draws[draw.ID] = draw
+	}
+}
+
+func (h *HealthState) recordSync(draw DrawRecord, err error, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.draws[draw.ID] = draw
+	if err != nil {
+		h.lastError[draw.ID] = err.Error()
+		return
+	}
+	h.lastSuccess[draw.ID] = now
+	delete(h.lastError, draw.ID)
+}
+
+func staleAfter() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("HEALTH_STALE_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = 30
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// Draws count from when the daemon started until their first successful sync. Off hours
+// polls are hourly, so staleness is counted from the start of today's match hours at the latest.
+func isStale(draw DrawRecord, lastSuccess time.Time, now time.Time, after time.Duration) bool {
+	if draw.State != DrawStateInProgress || !inMatchHours(draw, now) {
+		return false
+	}
+
+	local := now.In(drawLocation(draw))
+	since := time.Date(local.Year(), local.Month(), local.Day(), matchHoursStart, 0, 0, 0, local.Location())
+	if lastSuccess.After(since) {
+		since = lastSuccess
+	}
+	return now.Sub(since) > after
+}
+
+func (h *HealthState) report(now time.Time) (HealthReport, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	report := HealthReport{Status: "ok", Draws: []DrawHealth{}}
+	after := staleAfter()
+	healthy := true
+
+	for _, draw := range h.draws {
+		drawHealth := DrawHealth{DrawID: draw.ID, Name: draw.Name, Event: draw.Event, State: draw.State, LastError: h.lastError[draw.ID]}
+
+		since := h.started
+		if last, ok := h.lastSuccess[draw.ID]; ok {
+			drawHealth.LastSuccess = &last
+			since = last
+		}
+
+		if isStale(draw, since, now, after) {
+			drawHealth.Stale = true
+			healthy = false
+		}
+		report.Draws = append(report.Draws, drawHealth)
+	}
+
+	sort.Slice(report.Draws, func(i, j int) bool {
+		return report.Draws[i].DrawID < report.Draws[j].DrawID
+	})
+
+	if !healthy {
+		report.Status = "stale"
+	}
+	return report, healthy
+}
+
+func writeHealth(w http.ResponseWriter, report HealthReport, healthy bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if !healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("Error writing health report", "error", err)
+	}
+}
+
+func (h *HealthState) healthz(w http.ResponseWriter, r *http.Request) {
+	report, healthy := h.report(time.Now())
+	writeHealth(w, report, healthy)
+}
+
+func (h *HealthState) readyz(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	report, healthy := h.report(now)
+
+	report.Pocketbase, report.Token = "ok", "ok"
+	if err := h.checkPocketbase(); err != nil {
+		report.Pocketbase = err.Error()
+		report.Status = "not ready"
+		healthy = false
+	}
+	if err := h.tokenStatus(now); err != nil {
+		report.Token = err.Error()
+		report.Status = "not ready"
+		healthy = false
+	}
+
+	writeHealth(w, report, healthy)
+}
+
+// Checks the token again once it changes or the last check is older than tokenCheckInterval
+func (h *HealthState) tokenStatus(now time.Time) error {
+	h.mu.Lock()
+	token, check := h.token, h.tokenCheck
+	h.mu.Unlock()
+
+	if !check.at.IsZero() && check.token == token && now.Sub(check.at) < tokenCheckInterval {
+		return check.err
+	}
+
+	err := h.checkToken(token)
+
+	h.mu.Lock()
+	h.tokenCheck = TokenCheck{token: token, err: err, at: now}
+	h.mu.Unlock()
+
+	return err
+}
+
+func checkPocketbaseHealth() error {
+	url := fmt.Sprintf(`%s/api/health`, os.Getenv("BASE_URL"))
+
+	res, err := makeHTTPRequest("GET", url, "", nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("pocketbase health returned %s", res.Status)
+	}
+	return nil
+}
+
+func checkTokenValid(token string) error {
+	if token == "" {
+		return fmt.Errorf("not logged in")
+	}
+
+	url := fmt.Sprintf(`%s/api/collections/user/auth-refresh`, os.Getenv("BASE_URL"))
+
+	res, err := makeHTTPRequest("POST", url, token, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("token check returned %s", res.Status)
+	}
+	return nil
+}
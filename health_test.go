@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthStale(t *testing.T) {
+	t.Parallel()
+
+	started := time.Date(2025, 1, 20, 13, 0, 0, 0, time.UTC)
+	health := newHealthState(started)
+	health.setDraws([]DrawRecord{
+		{ID: "a", State: DrawStateInProgress, Timezone: "UTC"},
+		{ID: "b", State: DrawStatePredictionsOpen, Timezone: "UTC"},
+	})
+
+	// Just started, nothing is stale yet
+	report, healthy := health.report(started.Add(10 * time.Minute))
+	assert.True(t, healthy)
+	assert.Equal(t, report.Status, "ok")
+
+	// No successful sync since the daemon started
+	report, healthy = health.report(started.Add(time.Hour))
+	assert.False(t, healthy)
+	assert.Equal(t, report.Status, "stale")
+	assert.True(t, report.Draws[0].Stale)
+	assert.False(t, report.Draws[1].Stale)
+
+	health.recordSync(DrawRecord{ID: "a", State: DrawStateInProgress, Timezone: "UTC"}, nil, started.Add(50*time.Minute))
+	_, healthy = health.report(started.Add(time.Hour))
+	assert.True(t, healthy)
+
+	// Failures don't count as a sync
+	health.recordSync(DrawRecord{ID: "a", State: DrawStateInProgress, Timezone: "UTC"}, errors.New("invalid event"), started.Add(70*time.Minute))
+	report, healthy = health.report(started.Add(90 * time.Minute))
+	assert.False(t, healthy)
+	assert.Equal(t, report.Draws[0].LastError, "invalid event")
+
+	// Outside match hours the draw isn't polled often enough to be stale
+	_, healthy = health.report(time.Date(2025, 1, 21, 3, 0, 0, 0, time.UTC))
+	assert.True(t, healthy)
+
+	// Last synced by the hourly off hours poll, not stale until match hours have run for a while
+	health.recordSync(DrawRecord{ID: "a", State: DrawStateInProgress, Timezone: "UTC"}, nil, time.Date(2025, 1, 21, 9, 20, 0, 0, time.UTC))
+	_, healthy = health.report(time.Date(2025, 1, 21, 10, 5, 0, 0, time.UTC))
+	assert.True(t, healthy)
+	_, healthy = health.report(time.Date(2025, 1, 21, 10, 31, 0, 0, time.UTC))
+	assert.False(t, healthy)
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	t.Parallel()
+
+	health := newHealthState(time.Now())
+	health.setDraws([]DrawRecord{{ID: "a", State: DrawStatePublished}})
+	health.setToken("token")
+
+	pocketbaseErr := error(nil)
+	tokenChecks := 0
+	health.checkPocketbase = func() error { return pocketbaseErr }
+	health.checkToken = func(token string) error {
+		tokenChecks++
+		if token != "token" {
+			return errors.New("token check returned 401 Unauthorized")
+		}
+		return nil
+	}
+
+	get := func(handler http.HandlerFunc) (int, HealthReport) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest("GET", "/", nil))
+
+		var report HealthReport
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+		return recorder.Code, report
+	}
+
+	code, report := get(health.healthz)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, len(report.Draws), 1)
+
+	code, report = get(health.readyz)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, report.Pocketbase, "ok")
+
+	pocketbaseErr = errors.New("connection refused")
+	code, report = get(health.readyz)
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+	assert.Equal(t, report.Pocketbase, "connection refused")
+
+	// The token check is reused until the token changes
+	assert.Equal(t, tokenChecks, 1)
+
+	pocketbaseErr = nil
+	health.setToken("expired")
+	code, report = get(health.readyz)
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+	assert.Equal(t, report.Status, "not ready")
+	assert.Equal(t, tokenChecks, 2)
+
+	// Liveness doesn't depend on Pocketbase
+	code, _ = get(health.healthz)
+	assert.Equal(t, code, http.StatusOK)
+}
+
+func TestHealthChecks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/health":
+			w.WriteHeader(http.StatusOK)
+		case "/api/collections/user/auth-refresh":
+			if r.Header.Get("Authorization") != "valid" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	defer server.Close()
+	t.Setenv("BASE_URL", server.URL)
+
+	assert.NoError(t, checkPocketbaseHealth())
+	assert.NoError(t, checkTokenValid("valid"))
+	assert.Error(t, checkTokenValid("expired"))
+	assert.Error(t, checkTokenValid(""))
+}
@@ -1,32 +1,68 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
-func printWithTimestamp(a ...any) {
-	timestamp := time.Now().Format("2006/01/02 15:04:05")
-	message := fmt.Sprintf("%s %v", timestamp, a)
-	formatted := strings.ReplaceAll(strings.ReplaceAll(message, "[", ""), "]", "")
-	fmt.Println(formatted)
-}
-
 func trim(s string) string {
 	return strings.Trim(s, " \n\r")
 }
 
+// Pocketbase returns datetimes as UTC, e.g. "2025-01-19 12:00:00.000Z"
+func parsePocketbaseTime(s string) (time.Time, error) {
+	layouts := []string{"2006-01-02 15:04:05.000Z", "2006-01-02 15:04:05Z", "2006-01-02 15:04:05.000", "2006-01-02 15:04:05", "2006-01-02"}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid Pocketbase time: %q", s)
+}
+
 func hasAlphabet(input string) bool {
 	hasAlphabetPattern := regexp.MustCompile("[a-zA-Z]")
 	return hasAlphabetPattern.MatchString(input)
 }
 
+// Local files persisted between runs, e.g. pending corrections
+func statePath(name string) string {
+	dir := os.Getenv("STATE_DIR")
+	if dir == "" {
+		dir = "sync_state"
+	}
+	return filepath.Join(dir, name)
+}
+
+func appendJSONLine(filename string, v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
 func toSlotSlice(sr []SlotRecord) SlotSlice {
 	result := SlotSlice{}
 	for _, record := range sr {
@@ -36,10 +72,12 @@ func toSlotSlice(sr []SlotRecord) SlotSlice {
 			idField := fmt.Sprintf("Set%dID", i)
 			gamesField := fmt.Sprintf("Set%dGames", i)
 			tiebreakField := fmt.Sprintf("Set%dTiebreak", i)
+			lockedField := fmt.Sprintf("Set%dLocked", i)
 
 			idValue := reflect.ValueOf(record).FieldByName(idField)
 			gamesValue := reflect.ValueOf(record).FieldByName(gamesField)
 			tiebreakValue := reflect.ValueOf(record).FieldByName(tiebreakField)
+			lockedValue := reflect.ValueOf(record).FieldByName(lockedField)
 
 			if gamesValue.IsValid() && !gamesValue.IsNil() {
 				sets.add(Set{
@@ -48,6 +86,7 @@ func toSlotSlice(sr []SlotRecord) SlotSlice {
 					Number:     i,
 					Games:      *gamesValue.Interface().(*int),
 					Tiebreak:   *tiebreakValue.Interface().(*int),
+					Locked:     lockedValue.Bool(),
 				})
 			} else {
 				break
@@ -62,12 +101,15 @@ func toSlotSlice(sr []SlotRecord) SlotSlice {
 			Name:     record.Name,
 			Seed:     record.Seed,
 			Sets:     sets,
+			Locked:   record.Locked,
 		})
 	}
 	return result
 }
 
-func getUpdates(scraped SlotSlice, current SlotSlice, seeds map[string]string) (SlotSlice, SlotSlice, SetSlice, SetSlice) {
+// Pass a nil tracker to disable correction mode, so slots with a name are never cleared or replaced
+// and set scores are never deleted
+func getUpdates(scraped SlotSlice, current SlotSlice, seeds map[string]string, tracker *CorrectionTracker) (SlotSlice, SlotSlice, SetSlice, SetSlice, SetSlice, CorrectionSlice) {
 	scrapedMap := make(map[SlotKey]Slot)
 	currentMap := make(map[SlotKey]Slot)
 	allKeys := make(map[SlotKey]bool)
@@ -107,6 +149,8 @@ func getUpdates(scraped SlotSlice, current SlotSlice, seeds map[string]string) (
 	updatedSlots := SlotSlice{}
 	newSets := SetSlice{}
 	updatedSets := SetSlice{}
+	deletedSets := SetSlice{}
+	corrections := CorrectionSlice{}
 
 	for _, key := range keys {
 		scrapedSlot, scrapedExists := scrapedMap[key]
@@ -128,9 +172,70 @@ func getUpdates(scraped SlotSlice, current SlotSlice, seeds map[string]string) (
 
 		// Existing slot isn't scraped
 		if !scrapedExists {
+			if tracker != nil && currentSlot.Name != "" {
+				scrapedSlot = Slot{DrawID: currentSlot.DrawID, Round: key.Round, Position: key.Position}
+			} else {
+				continue
+			}
+		}
+
+		// Slots locked by an admin are never overwritten, see getLockConflicts
+		if currentSlot.Locked {
 			continue
 		}
 
+		// Correction mode, scraped slot would clear or replace an existing name
+		if tracker != nil {
+			trackerKey := correctionKey(currentSlot.DrawID, key.Round, key.Position)
+
+			if currentSlot.Name == "" || scrapedSlot.Name == currentSlot.Name {
+				tracker.reset(trackerKey)
+			} else {
+				// Locked set scores can't be deleted, so the slot can't be corrected
+				if hasLockedSet(currentSlot) {
+					continue
+				}
+
+				confirmed, count := tracker.confirm(trackerKey, scrapedSlot.Name)
+				if !confirmed {
+					continue
+				}
+
+				correctedSlot := Slot{
+					ID:       currentSlot.ID,
+					DrawID:   currentSlot.DrawID,
+					Round:    currentSlot.Round,
+					Position: currentSlot.Position,
+					Name:     scrapedSlot.Name,
+					Seed:     seeds[scrapedSlot.Name],
+					Sets:     scrapedSlot.Sets,
+				}
+
+				corrections.add(Correction{
+					DrawID:        currentSlot.DrawID,
+					Round:         key.Round,
+					Position:      key.Position,
+					Before:        currentSlot,
+					After:         correctedSlot,
+					Confirmations: count,
+				})
+
+				// Existing sets are deleted with the correction, so all scraped sets are new
+				for _, set := range currentSlot.Sets {
+					deletedSets.add(set)
+				}
+				for _, set := range scrapedSlot.Sets {
+					newSets.add(Set{
+						DrawSlotID: currentSlot.ID,
+						Number:     set.Number,
+						Games:      set.Games,
+						Tiebreak:   set.Tiebreak,
+					})
+				}
+				continue
+			}
+		}
+
 		// Update set scores
 		// Scraped slots and sets don't have IDs so we can't compare them directly
 		for j, scrapedSet := range scrapedSlot.Sets {
@@ -139,7 +244,11 @@ func getUpdates(scraped SlotSlice, current SlotSlice, seeds map[string]string) (
 
 				// Current and scraped sets are in order on each slot so set numbers should match
 				if currentSet.Number != scrapedSet.Number {
-					log.Println("Set numbers don't match for Set with ID:", currentSet.ID, "Current:", currentSet.Number, "Scraped:", scrapedSet.Number)
+					slog.Warn("Set numbers don't match", "set_id", currentSet.ID, "current", currentSet.Number, "scraped", scrapedSet.Number)
+					continue
+				}
+
+				if currentSet.Locked {
 					continue
 				}
 
@@ -163,6 +272,22 @@ func getUpdates(scraped SlotSlice, current SlotSlice, seeds map[string]string) (
 			}
 		}
 
+		// Delete sets no longer on the site, e.g. a provisional set from a suspended match
+		if tracker != nil {
+			trackerKey := correctionKey(currentSlot.DrawID, key.Round, key.Position) + "/sets"
+
+			if len(scrapedSlot.Sets) >= len(currentSlot.Sets) {
+				tracker.reset(trackerKey)
+			} else if confirmed, _ := tracker.confirm(trackerKey, strconv.Itoa(len(scrapedSlot.Sets))); confirmed {
+				tracker.reset(trackerKey)
+				for _, set := range currentSlot.Sets[len(scrapedSlot.Sets):] {
+					if !set.Locked {
+						deletedSets.add(set)
+					}
+				}
+			}
+		}
+
 		newName := scrapedSlot.Name
 		newSeed := seeds[newName]
 
@@ -189,7 +314,146 @@ func getUpdates(scraped SlotSlice, current SlotSlice, seeds map[string]string) (
 		updatedSlots.add(updatedSlot)
 	}
 
-	return newSlots, updatedSlots, newSets, updatedSets
+	return newSlots, updatedSlots, newSets, updatedSets, deletedSets, corrections
+}
+
+// Drops the set changes of corrections whose slot wasn't written, so a failed correction
+// doesn't delete the old player's sets or attach the new player's sets to the slot
+func withoutFailedCorrections(sets SetSlice, corrections CorrectionSlice, written map[string]bool) SetSlice {
+	failed := make(map[string]bool)
+	for _, correction := range corrections {
+		if !written[correction.After.ID] {
+			failed[correction.After.ID] = true
+		}
+	}
+
+	result := SetSlice{}
+	for _, set := range sets {
+		if !failed[set.DrawSlotID] {
+			result.add(set)
+		}
+	}
+	return result
+}
+
+// Draw size from the number of round 1 slots on the scraped page
+func detectDrawSize(slots SlotSlice) int {
+	size := 0
+	for _, slot := range slots {
+		if slot.Round == 1 {
+			size++
+		}
+	}
+	return size
+}
+
+func isPowerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
+
+// Describes a mismatch between the draw record and the scraped page
+func drawSizeDiagnostic(draw DrawRecord, slots SlotSlice) string {
+	rounds := make(map[int]int)
+	lastRound := 0
+	for _, slot := range slots {
+		rounds[slot.Round]++
+		lastRound = max(lastRound, slot.Round)
+	}
+
+	counts := []string{}
+	for round := 1; round <= lastRound; round++ {
+		counts = append(counts, fmt.Sprintf("%d:%d", round, rounds[round]))
+	}
+
+	detected := detectDrawSize(slots)
+	diagnostic := fmt.Sprintf("Draw size mismatch for %s %s %d: draw record size is %d but the page has %d round 1 slots (slots per round %s)",
+		draw.Name, draw.Event, draw.Year, draw.Size, detected, strings.Join(counts, " "))
+
+	if !isPowerOfTwo(detected) || len(slots) != detected*2-1 {
+		diagnostic += ". The page looks incomplete, check the draw URL"
+	} else {
+		diagnostic += fmt.Sprintf(". Set the draw size to %d or set AUTO_CORRECT_DRAW_SIZE=true", detected)
+	}
+
+	return diagnostic
+}
+
+func hasLockedSet(slot Slot) bool {
+	for _, set := range slot.Sets {
+		if set.Locked {
+			return true
+		}
+	}
+	return false
+}
+
+// Lists scraped values that differ from locked slots and sets, which getUpdates skips
+func getLockConflicts(scraped SlotSlice, current SlotSlice, seeds map[string]string) LockConflictSlice {
+	conflicts := LockConflictSlice{}
+
+	scrapedMap := make(map[SlotKey]Slot)
+	for _, slot := range scraped {
+		scrapedMap[SlotKey{Round: slot.Round, Position: slot.Position}] = slot
+	}
+
+	for _, currentSlot := range current {
+		scrapedSlot, ok := scrapedMap[SlotKey{Round: currentSlot.Round, Position: currentSlot.Position}]
+		if !ok {
+			continue
+		}
+
+		if currentSlot.Locked && scrapedSlot.Name != "" {
+			if scrapedSlot.Name != currentSlot.Name {
+				conflicts.add(LockConflict{
+					Collection: "draw_slot",
+					RecordID:   currentSlot.ID,
+					Round:      currentSlot.Round,
+					Position:   currentSlot.Position,
+					Field:      "name",
+					Locked:     currentSlot.Name,
+					Scraped:    scrapedSlot.Name,
+				})
+			}
+
+			if seed := seeds[scrapedSlot.Name]; seed != currentSlot.Seed {
+				conflicts.add(LockConflict{
+					Collection: "draw_slot",
+					RecordID:   currentSlot.ID,
+					Round:      currentSlot.Round,
+					Position:   currentSlot.Position,
+					Field:      "seed",
+					Locked:     currentSlot.Seed,
+					Scraped:    seed,
+				})
+			}
+		}
+
+		for j, currentSet := range currentSlot.Sets {
+			if !currentSlot.Locked && !currentSet.Locked {
+				continue
+			}
+
+			scrapedSet := "none"
+			if j < len(scrapedSlot.Sets) {
+				if scrapedSlot.Sets[j].Games == currentSet.Games && scrapedSlot.Sets[j].Tiebreak == currentSet.Tiebreak {
+					continue
+				}
+				scrapedSet = fmt.Sprintf("%d(%d)", scrapedSlot.Sets[j].Games, scrapedSlot.Sets[j].Tiebreak)
+			}
+
+			conflicts.add(LockConflict{
+				Collection: "set_score",
+				RecordID:   currentSet.ID,
+				Round:      currentSlot.Round,
+				Position:   currentSlot.Position,
+				Field:      fmt.Sprintf("set%d", currentSet.Number),
+				Locked:     fmt.Sprintf("%d(%d)", currentSet.Games, currentSet.Tiebreak),
+				Scraped:    scrapedSet,
+			})
+		}
+	}
+
+	return conflicts
 }
 
 func saveHTMLToFile(html, filename string) error {
@@ -210,19 +474,19 @@ func (m *MockScraper) scrape(targetURL string) string {
 	if strings.Contains(targetURL, "atptour.com") {
 		html, err := readHTMLFromFile("scraped_pages/atp.html")
 		if err != nil {
-			log.Println("Error reading HTML from ATP file:", err)
+			slog.Error("Error reading HTML from ATP file", "error", err)
 			return ""
 		}
 		return html
 	} else if strings.Contains(targetURL, "wtatennis.com") {
 		html, err := readHTMLFromFile("scraped_pages/wta.html")
 		if err != nil {
-			log.Println("Error reading HTML from WTA file:", err)
+			slog.Error("Error reading HTML from WTA file", "error", err)
 			return ""
 		}
 		return html
 	}
-	log.Println("Unknown URL:", targetURL)
+	slog.Error("Unknown URL", "url", targetURL)
 	return ""
 }
 
@@ -235,12 +499,12 @@ func (s *RealScraperSaveFile) scrape(targetURL string) string {
 	if strings.Contains(targetURL, "atptour.com") {
 		err := saveHTMLToFile(html, "scraped_pages/atp.html")
 		if err != nil {
-			log.Println("Error saving ATP HTML to file:", err)
+			slog.Error("Error saving ATP HTML to file", "error", err)
 		}
 	} else if strings.Contains(targetURL, "wtatennis.com") {
 		err := saveHTMLToFile(html, "scraped_pages/wta.html")
 		if err != nil {
-			log.Println("Error saving WTA HTML to file:", err)
+			slog.Error("Error saving WTA HTML to file", "error", err)
 		}
 	}
 
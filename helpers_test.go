@@ -69,7 +69,7 @@ func TestGetUpdates(t *testing.T) {
 	t.Parallel()
 
 	t.Run("Add a slot", func(t *testing.T) {
-		newSlots, updatedSlots, newSets, updatedSets := getUpdates(allFilled, twoFilled, seeds)
+		newSlots, updatedSlots, newSets, updatedSets, _, _ := getUpdates(allFilled, twoFilled, seeds, nil)
 		assert := assert.New(t)
 		assert.Equal(newSlots, SlotSlice{
 			Slot{ID: "ccc", DrawID: "draw1", Round: 2, Position: 1, Name: "Roger Federer", Seed: "(1)", Sets: setScoresC},
@@ -83,7 +83,7 @@ func TestGetUpdates(t *testing.T) {
 	})
 
 	t.Run("Add all slots", func(t *testing.T) {
-		newSlots, updatedSlots, newSets, updatedSets := getUpdates(allFilled, SlotSlice{}, seeds)
+		newSlots, updatedSlots, newSets, updatedSets, _, _ := getUpdates(allFilled, SlotSlice{}, seeds, nil)
 		assert := assert.New(t)
 		assert.Equal(newSlots, SlotSlice{
 			Slot{ID: "aaa", DrawID: "draw1", Round: 1, Position: 1, Name: "Roger Federer", Seed: "(1)", Sets: setScoresA},
@@ -103,7 +103,7 @@ func TestGetUpdates(t *testing.T) {
 	})
 
 	t.Run("Update slot name", func(t *testing.T) {
-		newSlots, updatedSlots, newSets, updatedSets := getUpdates(twoFilledOneWithName, twoFilledOneBlank, seeds)
+		newSlots, updatedSlots, newSets, updatedSets, _, _ := getUpdates(twoFilledOneWithName, twoFilledOneBlank, seeds, nil)
 		assert := assert.New(t)
 		assert.Equal(newSlots, SlotSlice{})
 		assert.Equal(updatedSlots, SlotSlice{
@@ -114,7 +114,7 @@ func TestGetUpdates(t *testing.T) {
 	})
 
 	t.Run("Add slot score", func(t *testing.T) {
-		newSlots, updatedSlots, newSets, updatedSets := getUpdates(allFilled, twoFilledOneWithName, seeds)
+		newSlots, updatedSlots, newSets, updatedSets, _, _ := getUpdates(allFilled, twoFilledOneWithName, seeds, nil)
 		assert := assert.New(t)
 		assert.Equal(newSlots, SlotSlice{})
 		assert.Equal(updatedSlots, SlotSlice{})
@@ -126,7 +126,7 @@ func TestGetUpdates(t *testing.T) {
 	})
 
 	t.Run("Update slot name and add score", func(t *testing.T) {
-		newSlots, updatedSlots, newSets, updatedSets := getUpdates(allFilled, twoFilledOneBlank, seeds)
+		newSlots, updatedSlots, newSets, updatedSets, _, _ := getUpdates(allFilled, twoFilledOneBlank, seeds, nil)
 		assert := assert.New(t)
 		assert.Equal(newSlots, SlotSlice{})
 		assert.Equal(updatedSlots, SlotSlice{
@@ -140,7 +140,7 @@ func TestGetUpdates(t *testing.T) {
 	})
 
 	t.Run("Update and add score", func(t *testing.T) {
-		newSlots, updatedSlots, newSets, updatedSets := getUpdates(allFilled, allFilledPartialSets, seeds)
+		newSlots, updatedSlots, newSets, updatedSets, _, _ := getUpdates(allFilled, allFilledPartialSets, seeds, nil)
 		assert := assert.New(t)
 		assert.Equal(newSlots, SlotSlice{})
 		assert.Equal(updatedSlots, SlotSlice{})
@@ -157,7 +157,7 @@ func TestGetUpdates(t *testing.T) {
 	})
 
 	t.Run("Update all slots", func(t *testing.T) {
-		newSlots, updatedSlots, newSets, updatedSets := getUpdates(allFilled, allBlank, seeds)
+		newSlots, updatedSlots, newSets, updatedSets, _, _ := getUpdates(allFilled, allBlank, seeds, nil)
 		assert := assert.New(t)
 		assert.Equal(newSlots, SlotSlice{})
 		assert.Equal(updatedSlots, SlotSlice{
@@ -177,7 +177,7 @@ func TestGetUpdates(t *testing.T) {
 	})
 
 	t.Run("Scraped all blanks, do not clear", func(t *testing.T) {
-		newSlots, updatedSlots, newSets, updatedSets := getUpdates(allBlank, allFilled, seeds)
+		newSlots, updatedSlots, newSets, updatedSets, _, _ := getUpdates(allBlank, allFilled, seeds, nil)
 		assert := assert.New(t)
 		assert.Equal(newSlots, SlotSlice{})
 		assert.Equal(updatedSlots, SlotSlice{})
@@ -186,7 +186,7 @@ func TestGetUpdates(t *testing.T) {
 	})
 
 	t.Run("Scraped one blank, do not clear", func(t *testing.T) {
-		newSlots, updatedSlots, newSets, updatedSets := getUpdates(twoFilledOneBlank, allFilled, seeds)
+		newSlots, updatedSlots, newSets, updatedSets, _, _ := getUpdates(twoFilledOneBlank, allFilled, seeds, nil)
 		assert := assert.New(t)
 		assert.Equal(newSlots, SlotSlice{})
 		assert.Equal(updatedSlots, SlotSlice{})
@@ -212,7 +212,7 @@ func TestGetUpdates(t *testing.T) {
 		}
 
 		// only round 1 slot 2 should be updated
-		newSlots, updatedSlots, newSets, updatedSets := getUpdates(scraped, current, seeds)
+		newSlots, updatedSlots, newSets, updatedSets, _, _ := getUpdates(scraped, current, seeds, nil)
 		assert := assert.New(t)
 		assert.Equal(newSlots, SlotSlice{})
 		assert.Equal(updatedSlots, SlotSlice{
@@ -226,7 +226,7 @@ func TestGetUpdates(t *testing.T) {
 	})
 
 	t.Run("No changes", func(t *testing.T) {
-		newSlots, updatedSlots, newSets, updatedSets := getUpdates(allFilled, allFilled, seeds)
+		newSlots, updatedSlots, newSets, updatedSets, _, _ := getUpdates(allFilled, allFilled, seeds, nil)
 		assert := assert.New(t)
 		assert.Equal(newSlots, SlotSlice{})
 		assert.Equal(updatedSlots, SlotSlice{})
@@ -235,7 +235,7 @@ func TestGetUpdates(t *testing.T) {
 	})
 
 	t.Run("Empty scrape", func(t *testing.T) {
-		newSlots, updatedSlots, newSets, updatedSets := getUpdates(SlotSlice{}, allFilled, seeds)
+		newSlots, updatedSlots, newSets, updatedSets, _, _ := getUpdates(SlotSlice{}, allFilled, seeds, nil)
 		assert := assert.New(t)
 		assert.Equal(newSlots, SlotSlice{})
 		assert.Equal(updatedSlots, SlotSlice{})
@@ -308,3 +308,261 @@ func TestNoAlphabet(t *testing.T) {
 		assert.Equal(hasAlphabet(item.s), item.expected)
 	}
 }
+
+func TestGetUpdatesCorrections(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Blank scrape clears slot after confirmations", func(t *testing.T) {
+		tracker := newCorrectionTracker(2, "")
+		assert := assert.New(t)
+
+		_, updatedSlots, newSets, updatedSets, deletedSets, corrections := getUpdates(twoFilledOneBlank, allFilled, seeds, tracker)
+		assert.Equal(updatedSlots, SlotSlice{})
+		assert.Equal(newSets, SetSlice{})
+		assert.Equal(updatedSets, SetSlice{})
+		assert.Equal(deletedSets, SetSlice{})
+		assert.Equal(corrections, CorrectionSlice{})
+
+		// The page hash isn't saved while the correction waits, so the next run parses it again
+		assert.True(tracker.hasPending("draw1"))
+		assert.False(tracker.hasPending("draw2"))
+		assert.False(synced(twoFilledOneBlank, allFilled, seeds, false, "draw1", "", tracker))
+
+		_, updatedSlots, newSets, updatedSets, deletedSets, corrections = getUpdates(twoFilledOneBlank, allFilled, seeds, tracker)
+		assert.Equal(updatedSlots, SlotSlice{})
+		assert.Equal(newSets, SetSlice{})
+		assert.Equal(updatedSets, SetSlice{})
+		assert.Equal(deletedSets, setScoresC)
+		assert.Equal(corrections, CorrectionSlice{
+			{
+				DrawID:        "draw1",
+				Round:         2,
+				Position:      1,
+				Before:        Slot{ID: "ccc", DrawID: "draw1", Round: 2, Position: 1, Name: "Roger Federer", Seed: "(1)", Sets: setScoresC},
+				After:         Slot{ID: "ccc", DrawID: "draw1", Round: 2, Position: 1, Name: "", Seed: "", Sets: SetSlice{}},
+				Confirmations: 2,
+			},
+		})
+
+		// Stays pending until the slot is written
+		assert.True(tracker.hasPending("draw1"))
+		tracker.resolve(corrections, map[string]bool{})
+		assert.True(tracker.hasPending("draw1"))
+		tracker.resolve(corrections, map[string]bool{"ccc": true})
+		assert.Empty(tracker.Pending)
+		assert.False(tracker.hasPending("draw1"))
+	})
+
+	t.Run("Failed correction keeps the slot's sets", func(t *testing.T) {
+		tracker := newCorrectionTracker(1, "")
+		scraped := SlotSlice{
+			Slot{ID: "aaa", DrawID: "draw1", Round: 1, Position: 1, Name: "Roger Federer", Seed: "(1)", Sets: setScoresA},
+			Slot{ID: "bbb", DrawID: "draw1", Round: 1, Position: 2, Name: "Rafael Nadal", Seed: "(2)", Sets: setScoresB},
+			Slot{ID: "ccc", DrawID: "draw1", Round: 2, Position: 1, Name: "Rafael Nadal", Seed: "(2)", Sets: SetSlice{
+				{Number: 1, Games: 7, Tiebreak: 5},
+			}},
+		}
+
+		_, _, newSets, _, deletedSets, corrections := getUpdates(scraped, allFilled, seeds, tracker)
+		assert := assert.New(t)
+		assert.Equal(withoutFailedCorrections(deletedSets, corrections, map[string]bool{}), SetSlice{})
+		assert.Equal(withoutFailedCorrections(newSets, corrections, map[string]bool{}), SetSlice{})
+		assert.Equal(withoutFailedCorrections(deletedSets, corrections, map[string]bool{"ccc": true}), setScoresC)
+
+		// Confirmed again on the next run, so the write is retried
+		tracker.resolve(corrections, map[string]bool{})
+		_, _, _, _, _, corrections = getUpdates(scraped, allFilled, seeds, tracker)
+		assert.Len(corrections, 1)
+	})
+
+	t.Run("Replaced name with new sets", func(t *testing.T) {
+		tracker := newCorrectionTracker(1, "")
+		scraped := SlotSlice{
+			Slot{ID: "aaa", DrawID: "draw1", Round: 1, Position: 1, Name: "Roger Federer", Seed: "(1)", Sets: setScoresA},
+			Slot{ID: "bbb", DrawID: "draw1", Round: 1, Position: 2, Name: "Rafael Nadal", Seed: "(2)", Sets: setScoresB},
+			Slot{ID: "ccc", DrawID: "draw1", Round: 2, Position: 1, Name: "Rafael Nadal", Seed: "(2)", Sets: SetSlice{
+				{Number: 1, Games: 7, Tiebreak: 5},
+			}},
+		}
+
+		_, updatedSlots, newSets, updatedSets, deletedSets, corrections := getUpdates(scraped, allFilled, seeds, tracker)
+		assert := assert.New(t)
+		assert.Equal(updatedSlots, SlotSlice{})
+		assert.Equal(newSets, SetSlice{
+			{ID: "", DrawSlotID: "ccc", Number: 1, Games: 7, Tiebreak: 5},
+		})
+		assert.Equal(updatedSets, SetSlice{})
+		assert.Equal(deletedSets, setScoresC)
+		assert.Len(corrections, 1)
+		assert.Equal(corrections[0].Before.Sets, setScoresC)
+		assert.Equal(corrections[0].After.Name, "Rafael Nadal")
+		assert.Equal(corrections[0].After.Seed, "(2)")
+	})
+
+	t.Run("Agreeing scrape resets pending correction", func(t *testing.T) {
+		tracker := newCorrectionTracker(2, "")
+		assert := assert.New(t)
+
+		getUpdates(twoFilledOneBlank, allFilled, seeds, tracker)
+		assert.Len(tracker.Pending, 1)
+
+		getUpdates(allFilled, allFilled, seeds, tracker)
+		assert.Empty(tracker.Pending)
+
+		_, _, _, _, _, corrections := getUpdates(twoFilledOneBlank, allFilled, seeds, tracker)
+		assert.Equal(corrections, CorrectionSlice{})
+	})
+
+	t.Run("Unscraped slot is cleared", func(t *testing.T) {
+		tracker := newCorrectionTracker(1, "")
+
+		_, _, _, _, _, corrections := getUpdates(twoFilled, allFilled, seeds, tracker)
+		assert := assert.New(t)
+		assert.Len(corrections, 1)
+		assert.Equal(corrections[0].Before.ID, "ccc")
+		assert.Equal(corrections[0].After.Name, "")
+	})
+}
+
+func TestGetUpdatesDeletedSets(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Shrinking sets ignored without correction mode", func(t *testing.T) {
+		newSlots, updatedSlots, newSets, updatedSets, deletedSets, _ := getUpdates(allFilledPartialSets, allFilled, seeds, nil)
+		assert := assert.New(t)
+		assert.Equal(newSlots, SlotSlice{})
+		assert.Equal(updatedSlots, SlotSlice{})
+		assert.Equal(newSets, SetSlice{})
+		assert.Equal(updatedSets, SetSlice{
+			{ID: "ss_a_1", DrawSlotID: "aaa", Number: 1, Games: 4, Tiebreak: 0},
+			{ID: "ss_b_1", DrawSlotID: "bbb", Number: 1, Games: 2, Tiebreak: 0},
+		})
+		assert.Equal(deletedSets, SetSlice{})
+	})
+
+	t.Run("Shrinking sets deleted after confirmations", func(t *testing.T) {
+		tracker := newCorrectionTracker(2, "")
+		assert := assert.New(t)
+
+		_, _, _, _, deletedSets, _ := getUpdates(allFilledPartialSets, allFilled, seeds, tracker)
+		assert.Equal(deletedSets, SetSlice{})
+
+		_, _, _, _, deletedSets, _ = getUpdates(allFilledPartialSets, allFilled, seeds, tracker)
+		assert.Equal(deletedSets, SetSlice{
+			{ID: "ss_a_2", DrawSlotID: "aaa", Number: 2, Games: 6, Tiebreak: 0},
+			{ID: "ss_b_2", DrawSlotID: "bbb", Number: 2, Games: 6, Tiebreak: 0},
+			{ID: "ss_c_1", DrawSlotID: "ccc", Number: 1, Games: 6, Tiebreak: 0},
+			{ID: "ss_c_2", DrawSlotID: "ccc", Number: 2, Games: 6, Tiebreak: 0},
+		})
+		assert.Empty(tracker.Pending)
+	})
+
+	t.Run("Shrinking sets not consecutive", func(t *testing.T) {
+		tracker := newCorrectionTracker(2, "")
+		assert := assert.New(t)
+
+		getUpdates(allFilledPartialSets, allFilled, seeds, tracker)
+		getUpdates(allFilled, allFilled, seeds, tracker)
+
+		_, _, _, _, deletedSets, _ := getUpdates(allFilledPartialSets, allFilled, seeds, tracker)
+		assert.Equal(deletedSets, SetSlice{})
+	})
+
+	t.Run("Different set count restarts confirmations", func(t *testing.T) {
+		tracker := newCorrectionTracker(2, "")
+		oneSet := SlotSlice{
+			Slot{ID: "aaa", DrawID: "draw1", Round: 1, Position: 1, Name: "Roger Federer", Seed: "(1)", Sets: setScoresA[:1]},
+		}
+		noSets := SlotSlice{
+			Slot{ID: "aaa", DrawID: "draw1", Round: 1, Position: 1, Name: "Roger Federer", Seed: "(1)", Sets: SetSlice{}},
+		}
+		current := SlotSlice{
+			Slot{ID: "aaa", DrawID: "draw1", Round: 1, Position: 1, Name: "Roger Federer", Seed: "(1)", Sets: setScoresA},
+		}
+		assert := assert.New(t)
+
+		getUpdates(oneSet, current, seeds, tracker)
+		_, _, _, _, deletedSets, _ := getUpdates(noSets, current, seeds, tracker)
+		assert.Equal(deletedSets, SetSlice{})
+
+		_, _, _, _, deletedSets, _ = getUpdates(noSets, current, seeds, tracker)
+		assert.Equal(deletedSets, setScoresA)
+	})
+}
+
+func TestGetUpdatesLocked(t *testing.T) {
+	t.Parallel()
+
+	lockedSets := SetSlice{
+		{ID: "ss_c_1", DrawSlotID: "ccc", Number: 1, Games: 7, Tiebreak: 0, Locked: true},
+		{ID: "ss_c_2", DrawSlotID: "ccc", Number: 2, Games: 6, Tiebreak: 0},
+		{ID: "ss_c_3", DrawSlotID: "ccc", Number: 3, Games: 6, Tiebreak: 0, Locked: true},
+	}
+
+	current := SlotSlice{
+		Slot{ID: "aaa", DrawID: "draw1", Round: 1, Position: 1, Name: "Roger Federer", Seed: "(1)", Sets: setScoresA},
+		Slot{ID: "bbb", DrawID: "draw1", Round: 1, Position: 2, Name: "Andy Murray", Seed: "", Sets: SetSlice{}, Locked: true},
+		Slot{ID: "ccc", DrawID: "draw1", Round: 2, Position: 1, Name: "Roger Federer", Seed: "(1)", Sets: lockedSets},
+	}
+
+	scraped := SlotSlice{
+		Slot{ID: "aaa", DrawID: "draw1", Round: 1, Position: 1, Name: "Roger Federer", Seed: "(1)", Sets: setScoresA},
+		Slot{ID: "bbb", DrawID: "draw1", Round: 1, Position: 2, Name: "Rafael Nadal", Seed: "(2)", Sets: setScoresB},
+		Slot{ID: "ccc", DrawID: "draw1", Round: 2, Position: 1, Name: "Roger Federer", Seed: "(1)", Sets: SetSlice{
+			{Number: 1, Games: 6, Tiebreak: 0},
+			{Number: 2, Games: 3, Tiebreak: 0},
+		}},
+	}
+
+	t.Run("Locked slots and sets are not overwritten", func(t *testing.T) {
+		tracker := newCorrectionTracker(1, "")
+
+		newSlots, updatedSlots, newSets, updatedSets, deletedSets, corrections := getUpdates(scraped, current, seeds, tracker)
+		assert := assert.New(t)
+		assert.Equal(newSlots, SlotSlice{})
+		assert.Equal(updatedSlots, SlotSlice{})
+		assert.Equal(newSets, SetSlice{})
+		assert.Equal(updatedSets, SetSlice{
+			{ID: "ss_c_2", DrawSlotID: "ccc", Number: 2, Games: 3, Tiebreak: 0},
+		})
+		assert.Equal(deletedSets, SetSlice{})
+		assert.Equal(corrections, CorrectionSlice{})
+	})
+
+	t.Run("Lock conflicts are listed", func(t *testing.T) {
+		conflicts := getLockConflicts(scraped, current, seeds)
+		assert.Equal(t, conflicts, LockConflictSlice{
+			{Collection: "draw_slot", RecordID: "bbb", Round: 1, Position: 2, Field: "name", Locked: "Andy Murray", Scraped: "Rafael Nadal"},
+			{Collection: "draw_slot", RecordID: "bbb", Round: 1, Position: 2, Field: "seed", Locked: "", Scraped: "(2)"},
+			{Collection: "set_score", RecordID: "ss_c_1", Round: 2, Position: 1, Field: "set1", Locked: "7(0)", Scraped: "6(0)"},
+			{Collection: "set_score", RecordID: "ss_c_3", Round: 2, Position: 1, Field: "set3", Locked: "6(0)", Scraped: "none"},
+		})
+	})
+
+	t.Run("No conflicts when scrape agrees", func(t *testing.T) {
+		conflicts := getLockConflicts(current, current, map[string]string{"Roger Federer": "(1)"})
+		assert.Equal(t, conflicts, LockConflictSlice{})
+	})
+}
+
+func TestDrawSize(t *testing.T) {
+	t.Parallel()
+
+	draw := DrawRecord{Name: "Australian Open", Event: "Men's Singles", Year: 2025, Size: 4}
+
+	t.Run("Detected from round 1", func(t *testing.T) {
+		assert := assert.New(t)
+		assert.Equal(detectDrawSize(allFilled), 2)
+		assert.Equal(detectDrawSize(SlotSlice{}), 0)
+	})
+
+	t.Run("Complete page suggests correction", func(t *testing.T) {
+		diagnostic := drawSizeDiagnostic(draw, allFilled)
+		assert.Equal(t, diagnostic, "Draw size mismatch for Australian Open Men's Singles 2025: draw record size is 4 but the page has 2 round 1 slots (slots per round 1:2 2:1). Set the draw size to 2 or set AUTO_CORRECT_DRAW_SIZE=true")
+	})
+
+	t.Run("Incomplete page", func(t *testing.T) {
+		diagnostic := drawSizeDiagnostic(draw, twoFilled)
+		assert.Equal(t, diagnostic, "Draw size mismatch for Australian Open Men's Singles 2025: draw record size is 4 but the page has 2 round 1 slots (slots per round 1:2). The page looks incomplete, check the draw URL")
+	})
+}
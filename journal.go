@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Every write made to Pocketbase is appended to a local JSONL journal, and optionally
+// to the sync_log collection, so changes can be traced back to the run that made them.
+
+type JournalEntry struct {
+	RunID      string          `json:"run_id"`
+	Timestamp  string          `json:"timestamp"`
+	DrawID     string          `json:"draw_id"`
+	SourceURL  string          `json:"source_url"`
+	Operation  string          `json:"operation"`
+	Collection string          `json:"collection"`
+	RecordID   string          `json:"record_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+}
+
+type Journal struct {
+	RunID  string
+	path   string
+	remote bool
+	token  string
+	draw   DrawRecord
+	slots  map[string]Slot
+	sets   map[string]Set
+}
+
+func newRunID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		slog.Error("Error generating run ID", "error", err)
+	}
+	return fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102T150405Z"), hex.EncodeToString(b))
+}
+
+func journalPath() string {
+	path := os.Getenv("JOURNAL_PATH")
+	if path == "" {
+		path = statePath("journal.jsonl")
+	}
+	return path
+}
+
+func newJournal(runID string, token string) *Journal {
+	return &Journal{
+		RunID:  runID,
+		path:   journalPath(),
+		remote: os.Getenv("SYNC_LOG_REMOTE") == "true",
+		token:  token,
+		slots:  make(map[string]Slot),
+		sets:   make(map[string]Set),
+	}
+}
+
+// Current slots are kept so before-images can be recorded for updates
+func (j *Journal) startDraw(draw DrawRecord, current SlotSlice) {
+	if j == nil {
+		return
+	}
+
+	j.draw = draw
+	j.slots = make(map[string]Slot)
+	j.sets = make(map[string]Set)
+
+	for _, slot := range current {
+		j.slots[slot.ID] = slot
+		for _, set := range slot.Sets {
+			j.sets[set.ID] = set
+		}
+	}
+}
+
+func slotPayload(slot *Slot) json.RawMessage {
+	if slot == nil {
+		return json.RawMessage("null")
+	}
+
+	return toRawJSON(CreateUpdateSlotReq{
+		DrawID:   slot.DrawID,
+		Round:    slot.Round,
+		Position: slot.Position,
+		Name:     slot.Name,
+		Seed:     slot.Seed,
+	})
+}
+
+func setPayload(set *Set) json.RawMessage {
+	if set == nil {
+		return json.RawMessage("null")
+	}
+
+	return toRawJSON(CreateUpdateSetReq{
+		DrawSlotID: set.DrawSlotID,
+		Number:     set.Number,
+		Games:      set.Games,
+		Tiebreak:   set.Tiebreak,
+	})
+}
+
+func toRawJSON(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("Error encoding journal payload", "error", err)
+		return json.RawMessage("null")
+	}
+	return data
+}
+
+func (j *Journal) recordSlot(operation string, id string, before *Slot, after *Slot) {
+	if j == nil {
+		return
+	}
+
+	if before == nil && operation != "create" {
+		if current, ok := j.slots[id]; ok {
+			before = &current
+		}
+	}
+
+	j.record(operation, "draw_slot", id, slotPayload(before), slotPayload(after))
+}
+
+func (j *Journal) recordSet(operation string, id string, before *Set, after *Set) {
+	if j == nil {
+		return
+	}
+
+	if before == nil && operation != "create" {
+		if current, ok := j.sets[id]; ok {
+			before = &current
+		}
+	}
+
+	j.record(operation, "set_score", id, setPayload(before), setPayload(after))
+}
+
+func (j *Journal) record(operation string, collection string, id string, before json.RawMessage, after json.RawMessage) {
+	if j == nil {
+		return
+	}
+
+	entry := JournalEntry{
+		RunID:      j.RunID,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		DrawID:     j.draw.ID,
+		SourceURL:  j.draw.Url,
+		Operation:  operation,
+		Collection: collection,
+		RecordID:   id,
+		Before:     before,
+		After:      after,
+	}
+
+	if err := appendJSONLine(j.path, entry); err != nil {
+		slog.Error("Error writing journal entry", "error", err)
+	}
+
+	if j.remote {
+		postSyncLog(entry, j.token)
+	}
+}
+
+func readJournalEntries(path string) ([]JournalEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []JournalEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		entry := JournalEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	t.Parallel()
+
+	draw := DrawRecord{ID: "draw1", Url: "https://www.atptour.com/en/scores/current/australian-open/580/draws"}
+
+	t.Run("Records before and after images", func(t *testing.T) {
+		journal := newJournal("run1", "")
+		journal.path = filepath.Join(t.TempDir(), "journal.jsonl")
+		journal.startDraw(draw, twoFilledOneBlank)
+
+		updated := Slot{ID: "ccc", DrawID: "draw1", Round: 2, Position: 1, Name: "Roger Federer", Seed: "(1)"}
+		journal.recordSlot("update", "ccc", nil, &updated)
+		journal.recordSet("create", "ss_new", nil, &Set{DrawSlotID: "ccc", Number: 1, Games: 6})
+		journal.recordSet("delete", "ss_a_2", &setScoresA[1], nil)
+
+		entries, err := readJournalEntries(journal.path)
+		assert := assert.New(t)
+		assert.NoError(err)
+		assert.Len(entries, 3)
+
+		assert.Equal(entries[0].RunID, "run1")
+		assert.Equal(entries[0].DrawID, "draw1")
+		assert.Equal(entries[0].SourceURL, draw.Url)
+		assert.Equal(entries[0].Collection, "draw_slot")
+		assert.Equal(entries[0].RecordID, "ccc")
+		assert.JSONEq(`{"draw_id":"draw1","round":2,"position":1,"name":"","seed":""}`, string(entries[0].Before))
+		assert.JSONEq(`{"draw_id":"draw1","round":2,"position":1,"name":"Roger Federer","seed":"(1)"}`, string(entries[0].After))
+
+		assert.Equal(entries[1].Operation, "create")
+		assert.JSONEq(`null`, string(entries[1].Before))
+		assert.JSONEq(`{"draw_slot_id":"ccc","number":1,"games":6,"tiebreak":0}`, string(entries[1].After))
+
+		assert.Equal(entries[2].Operation, "delete")
+		assert.JSONEq(`{"draw_slot_id":"aaa","number":2,"games":6,"tiebreak":0}`, string(entries[2].Before))
+		assert.JSONEq(`null`, string(entries[2].After))
+	})
+
+	t.Run("Nil journal is a no-op", func(t *testing.T) {
+		var journal *Journal
+		journal.startDraw(draw, allFilled)
+		journal.recordSlot("update", "aaa", nil, &allFilled[0])
+	})
+}
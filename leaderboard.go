@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math/bits"
+	"sort"
+	"strconv"
+)
+
+// Leaderboards are ranked by points, then these tie-breakers in order:
+// correct champion picks, most correct picks in later rounds, earliest submission.
+// Users still tied after all of them share a rank.
+
+type LeaderboardEntry struct {
+	UserID string
+	Points int
+	// Correct picks counted back from the champion slot, index 0 is the champion
+	CorrectFromEnd []int
+	FirstSubmitted string
+	Rank           int
+}
+
+// Round of the champion slot, e.g. round 8 for a 128 draw
+func championRound(size int) int {
+	return bits.Len(uint(size))
+}
+
+// Sums score records by user, sizes maps draw IDs to draw sizes
+func leaderboardEntries(scores []ScoreRecord, sizes map[string]int) []LeaderboardEntry {
+	entries := make(map[string]*LeaderboardEntry)
+	userIDs := []string{}
+
+	for _, score := range scores {
+		entry, ok := entries[score.UserID]
+		if !ok {
+			entry = &LeaderboardEntry{UserID: score.UserID}
+			entries[score.UserID] = entry
+			userIDs = append(userIDs, score.UserID)
+		}
+
+		entry.Points += score.Points
+
+		if score.FirstSubmitted != "" && (entry.FirstSubmitted == "" || score.FirstSubmitted < entry.FirstSubmitted) {
+			entry.FirstSubmitted = score.FirstSubmitted
+		}
+
+		lastRound := championRound(sizes[score.DrawID])
+		for roundStr, correct := range score.CorrectByRound {
+			round, err := strconv.Atoi(roundStr)
+			if err != nil || round > lastRound {
+				continue
+			}
+
+			fromEnd := lastRound - round
+			for len(entry.CorrectFromEnd) <= fromEnd {
+				entry.CorrectFromEnd = append(entry.CorrectFromEnd, 0)
+			}
+			entry.CorrectFromEnd[fromEnd] += correct
+		}
+	}
+
+	result := []LeaderboardEntry{}
+	for _, userID := range userIDs {
+		result = append(result, *entries[userID])
+	}
+	return result
+}
+
+// Negative if a ranks above b, zero if they are tied on every rule
+func compareEntries(a LeaderboardEntry, b LeaderboardEntry) int {
+	if a.Points != b.Points {
+		return b.Points - a.Points
+	}
+
+	// Champion picks are index 0, so this covers correct champion then later rounds
+	for i := 0; i < max(len(a.CorrectFromEnd), len(b.CorrectFromEnd)); i++ {
+		aCorrect, bCorrect := 0, 0
+		if i < len(a.CorrectFromEnd) {
+			aCorrect = a.CorrectFromEnd[i]
+		}
+		if i < len(b.CorrectFromEnd) {
+			bCorrect = b.CorrectFromEnd[i]
+		}
+		if aCorrect != bCorrect {
+			return bCorrect - aCorrect
+		}
+	}
+
+	switch {
+	case a.FirstSubmitted == b.FirstSubmitted:
+		return 0
+	case a.FirstSubmitted == "":
+		return 1
+	case b.FirstSubmitted == "":
+		return -1
+	case a.FirstSubmitted < b.FirstSubmitted:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func rankLeaderboard(entries []LeaderboardEntry) []LeaderboardEntry {
+	ranked := append([]LeaderboardEntry{}, entries...)
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if c := compareEntries(ranked[i], ranked[j]); c != 0 {
+			return c < 0
+		}
+		return ranked[i].UserID < ranked[j].UserID
+	})
+
+	for i := range ranked {
+		if i > 0 && compareEntries(ranked[i-1], ranked[i]) == 0 {
+			ranked[i].Rank = ranked[i-1].Rank
+		} else {
+			ranked[i].Rank = i + 1
+		}
+	}
+
+	return ranked
+}
+
+// Recomputes the draw and season leaderboards from the score collection
+func updateLeaderboards(draw DrawRecord, token string, journal *Journal) {
+	drawScores := getScores(draw.ID, token)
+	if drawScores == nil {
+		slog.Warn("Skipping draw leaderboard, couldn't get scores", "name", draw.Name, "event", draw.Event, "year", draw.Year)
+	} else {
+		entries := leaderboardEntries(drawScores, map[string]int{draw.ID: draw.Size})
+		filter := fmt.Sprintf(`(scope="draw"&&draw_id="%s")`, draw.ID)
+		saveLeaderboard("draw", draw.ID, draw.Year, rankLeaderboard(entries), filter, token, journal)
+	}
+
+	seasonDraws := getDrawsByYear(draw.Year, token)
+	if seasonDraws == nil {
+		slog.Warn("Skipping season leaderboard, couldn't get draws", "year", draw.Year)
+		return
+	}
+
+	seasonScores := []ScoreRecord{}
+	sizes := make(map[string]int)
+	for _, seasonDraw := range seasonDraws {
+		scores := getScores(seasonDraw.ID, token)
+		if scores == nil {
+			slog.Warn("Skipping season leaderboard, couldn't get scores", "year", draw.Year)
+			return
+		}
+		seasonScores = append(seasonScores, scores...)
+		sizes[seasonDraw.ID] = seasonDraw.Size
+	}
+
+	filter := fmt.Sprintf(`(scope="season"&&year=%d)`, draw.Year)
+	saveLeaderboard("season", "", draw.Year, rankLeaderboard(leaderboardEntries(seasonScores, sizes)), filter, token, journal)
+}
+
+// Upserts ranks, keeping the rank from the previous sync to show movement.
+// Users who no longer rank are removed.
+func saveLeaderboard(scope string, drawID string, year int, ranked []LeaderboardEntry, filter string, token string, journal *Journal) {
+	records := getLeaderboard(filter, token)
+	if records == nil {
+		slog.Warn("Skipping leaderboard, couldn't get existing ranks", "scope", scope)
+		return
+	}
+
+	existing := make(map[string]LeaderboardRecord)
+	for _, record := range records {
+		existing[record.UserID] = record
+	}
+
+	ranks := make(map[string]bool)
+
+	for _, entry := range ranked {
+		ranks[entry.UserID] = true
+		record, ok := existing[entry.UserID]
+
+		requestData := CreateUpdateLeaderboardReq{
+			Scope:  scope,
+			DrawID: drawID,
+			Year:   year,
+			UserID: entry.UserID,
+			Rank:   entry.Rank,
+			Points: entry.Points,
+		}
+
+		method, operation := "POST", "create"
+		before := toRawJSON(nil)
+		if ok {
+			if record.Rank == entry.Rank && record.Points == entry.Points && record.Movement == 0 {
+				continue
+			}
+
+			requestData.PreviousRank = record.Rank
+			requestData.Movement = record.Rank - entry.Rank
+			method, operation = "PATCH", "update"
+			before = toRawJSON(leaderboardRequest(record))
+		}
+
+		id, err := writeRecord(method, "leaderboard", record.ID, requestData, token)
+		if err != nil {
+			slog.Error("Error saving leaderboard", "user_id", entry.UserID, "error", err)
+			continue
+		}
+
+		journal.record(operation, "leaderboard", id, before, toRawJSON(requestData))
+	}
+
+	for _, record := range records {
+		if ranks[record.UserID] {
+			continue
+		}
+
+		if _, err := writeRecord("DELETE", "leaderboard", record.ID, nil, token); err != nil {
+			slog.Error("Error deleting leaderboard", "user_id", record.UserID, "error", err)
+			continue
+		}
+
+		journal.record("delete", "leaderboard", record.ID, toRawJSON(leaderboardRequest(record)), toRawJSON(nil))
+	}
+
+	slog.Info("Saved leaderboard", "scope", scope, "users", len(ranked))
+}
+
+func leaderboardRequest(record LeaderboardRecord) CreateUpdateLeaderboardReq {
+	return CreateUpdateLeaderboardReq{
+		Scope:        record.Scope,
+		DrawID:       record.DrawID,
+		Year:         record.Year,
+		UserID:       record.UserID,
+		Rank:         record.Rank,
+		PreviousRank: record.PreviousRank,
+		Movement:     record.Movement,
+		Points:       record.Points,
+	}
+}
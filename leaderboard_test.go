@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankLeaderboard(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Tie-breakers in order", func(t *testing.T) {
+		entries := []LeaderboardEntry{
+			{UserID: "late", Points: 10, CorrectFromEnd: []int{1, 1}, FirstSubmitted: "2025-01-11 10:00:00.000Z"},
+			{UserID: "no_champion", Points: 10, CorrectFromEnd: []int{0, 2, 5}, FirstSubmitted: "2025-01-10 10:00:00.000Z"},
+			{UserID: "most_points", Points: 12, CorrectFromEnd: []int{0}},
+			{UserID: "early", Points: 10, CorrectFromEnd: []int{1, 1}, FirstSubmitted: "2025-01-10 10:00:00.000Z"},
+			{UserID: "fewer_final", Points: 10, CorrectFromEnd: []int{1, 0, 9}, FirstSubmitted: "2025-01-09 10:00:00.000Z"},
+		}
+
+		ranked := rankLeaderboard(entries)
+		assert := assert.New(t)
+
+		users := []string{}
+		ranks := []int{}
+		for _, entry := range ranked {
+			users = append(users, entry.UserID)
+			ranks = append(ranks, entry.Rank)
+		}
+		assert.Equal(users, []string{"most_points", "early", "late", "fewer_final", "no_champion"})
+		assert.Equal(ranks, []int{1, 2, 3, 4, 5})
+	})
+
+	t.Run("Ties share a rank", func(t *testing.T) {
+		entries := []LeaderboardEntry{
+			{UserID: "bbb", Points: 5, CorrectFromEnd: []int{0, 1}, FirstSubmitted: "2025-01-10 10:00:00.000Z"},
+			{UserID: "aaa", Points: 5, CorrectFromEnd: []int{0, 1}, FirstSubmitted: "2025-01-10 10:00:00.000Z"},
+			{UserID: "ccc", Points: 3},
+		}
+
+		ranked := rankLeaderboard(entries)
+		assert := assert.New(t)
+		assert.Equal(ranked[0].UserID, "aaa")
+		assert.Equal(ranked[0].Rank, 1)
+		assert.Equal(ranked[1].UserID, "bbb")
+		assert.Equal(ranked[1].Rank, 1)
+		assert.Equal(ranked[2].Rank, 3)
+	})
+}
+
+func TestLeaderboardEntries(t *testing.T) {
+	t.Parallel()
+
+	// Season across a 128 draw and a 64 draw, both champion picks are index 0
+	scores := []ScoreRecord{
+		{DrawID: "slam", UserID: "user1", Points: 10, CorrectByRound: map[string]int{"2": 3, "8": 1}, FirstSubmitted: "2025-01-10 10:00:00.000Z"},
+		{DrawID: "masters", UserID: "user1", Points: 4, CorrectByRound: map[string]int{"6": 1, "7": 1}, FirstSubmitted: "2025-03-01 10:00:00.000Z"},
+		{DrawID: "slam", UserID: "user2", Points: 2, CorrectByRound: map[string]int{}},
+	}
+	sizes := map[string]int{"slam": 128, "masters": 64}
+
+	entries := leaderboardEntries(scores, sizes)
+	assert.Equal(t, entries, []LeaderboardEntry{
+		{UserID: "user1", Points: 14, CorrectFromEnd: []int{2, 1, 0, 0, 0, 0, 3}, FirstSubmitted: "2025-01-10 10:00:00.000Z"},
+		{UserID: "user2", Points: 2},
+	})
+}
+
+func TestSaveLeaderboardDeletesStaleRows(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		if r.Method == "GET" {
+			json.NewEncoder(w).Encode(LeaderboardRes{TotalPages: 1, Items: []LeaderboardRecord{
+				{ID: "l1", Scope: "draw", DrawID: "draw1", Year: 2025, UserID: "u1", Rank: 1, Points: 4},
+				{ID: "l2", Scope: "draw", DrawID: "draw1", Year: 2025, UserID: "u2", Rank: 2, Points: 1},
+			}})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	t.Setenv("BASE_URL", server.URL)
+
+	// u2 no longer has a score for the draw
+	ranked := []LeaderboardEntry{{UserID: "u1", Rank: 1, Points: 4}}
+	saveLeaderboard("draw", "draw1", 2025, ranked, `(scope="draw"&&draw_id="draw1")`, "token", nil)
+
+	assert.Equal(t, requests, []string{
+		"GET /api/collections/leaderboard/records",
+		"DELETE /api/collections/leaderboard/records/l2",
+	})
+}
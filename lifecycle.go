@@ -0,0 +1,126 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Draw lifecycle, stored in the state field of the draw record.
+// States only move forward, so a bad scrape can't send a draw back to an earlier state.
+const (
+	DrawStateDraft           = "draft"
+	DrawStateScheduled       = "scheduled"
+	DrawStatePublished       = "draw-published"
+	DrawStatePredictionsOpen = "predictions-open"
+	DrawStateInProgress      = "in-progress"
+	DrawStateCompleted       = "completed"
+	DrawStateArchived        = "archived"
+)
+
+// Days after end_date that getDraws still selects a draw, long enough for it to be archived
+const drawArchiveWindowDays = 7
+
+var drawStateOrder = map[string]int{
+	DrawStateDraft:           0,
+	"":                       0,
+	DrawStateScheduled:       1,
+	DrawStatePublished:       2,
+	DrawStatePredictionsOpen: 3,
+	DrawStateInProgress:      4,
+	DrawStateCompleted:       5,
+	DrawStateArchived:        6,
+}
+
+func nextDrawState(draw DrawRecord, slots SlotSlice, now time.Time) string {
+	next := computeDrawState(draw, slots, now)
+	if drawStateOrder[next] < drawStateOrder[draw.State] {
+		return draw.State
+	}
+	return next
+}
+
+func computeDrawState(draw DrawRecord, slots SlotSlice, now time.Time) string {
+	location := drawLocation(draw)
+
+	// The day after end_date at the tournament
+	if end, err := drawDate(draw.End_Date, location); err == nil {
+		if !now.Before(end.AddDate(0, 0, 1)) {
+			return DrawStateArchived
+		}
+	}
+
+	firstRound := 0
+	championFilled := false
+	for _, slot := range slots {
+		if slot.Name == "" {
+			continue
+		}
+		if slot.Round == 1 {
+			firstRound++
+		}
+		if slot.Round == championRound(draw.Size) && slot.Position == 1 {
+			championFilled = true
+		}
+	}
+
+	started := false
+	if start, err := drawDate(draw.Start_Date, location); err == nil {
+		started = !now.Before(start)
+	}
+
+	switch {
+	case championFilled:
+		return DrawStateCompleted
+	case firstRound == 0:
+		return DrawStateScheduled
+	case started || predictionsClosed(draw, now):
+		return DrawStateInProgress
+	case firstRound == draw.Size:
+		return DrawStatePredictionsOpen
+	default:
+		// Published with qualifier or lucky loser places still to be filled
+		return DrawStatePublished
+	}
+}
+
+// Scheduled draws are only scraped close to their start, when the draw is usually published
+func shouldScrape(draw DrawRecord, now time.Time) bool {
+	switch draw.State {
+	case DrawStateDraft, DrawStateCompleted, DrawStateArchived:
+		return false
+	case "", DrawStateScheduled:
+		leadDays, err := strconv.Atoi(os.Getenv("DRAW_PUBLISH_LEAD_DAYS"))
+		if err != nil {
+			leadDays = 3
+		}
+
+		start, err := drawDate(draw.Start_Date, drawLocation(draw))
+		if err != nil {
+			return true
+		}
+		return !now.Before(start.AddDate(0, 0, -leadDays))
+	default:
+		return true
+	}
+}
+
+// Saves the next state if it changed, and returns the draw with it
+func transitionDraw(draw DrawRecord, slots SlotSlice, token string, journal *Journal) DrawRecord {
+	next := nextDrawState(draw, slots, time.Now())
+	if next == draw.State {
+		return draw
+	}
+
+	update := map[string]string{"state": next}
+	if _, err := writeRecord("PATCH", "draw", draw.ID, update, token); err != nil {
+		slog.Error("Error updating draw state", "error", err)
+		return draw
+	}
+	journal.record("update", "draw", draw.ID, toRawJSON(map[string]string{"state": draw.State}), toRawJSON(update))
+
+	slog.Info("Draw state changed", "name", draw.Name, "event", draw.Event, "year", draw.Year, "from", draw.State, "to", next)
+	draw.State = next
+	return draw
+}
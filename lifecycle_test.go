@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Four player draw, champion slot is round 3
+var lifecycleDraw = DrawRecord{
+	Name:             "Australian Open",
+	Start_Date:       "2025-01-12 00:00:00.000Z",
+	End_Date:         "2025-01-26 00:00:00.000Z",
+	Prediction_Close: "2025-01-12 00:00:00.000Z",
+	Size:             4,
+	Timezone:         "UTC",
+}
+
+func lifecycleSlots(firstRound []string, champion string) SlotSlice {
+	slots := SlotSlice{}
+	for i, name := range firstRound {
+		slots.add(Slot{Round: 1, Position: i + 1, Name: name})
+	}
+	slots.add(Slot{Round: 2, Position: 1})
+	slots.add(Slot{Round: 2, Position: 2})
+	slots.add(Slot{Round: 3, Position: 1, Name: champion})
+	return slots
+}
+
+func TestNextDrawState(t *testing.T) {
+	t.Parallel()
+
+	beforeStart := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	duringDraw := time.Date(2025, 1, 20, 12, 0, 0, 0, time.UTC)
+	lastDay := time.Date(2025, 1, 26, 23, 59, 0, 0, time.UTC)
+	dayAfterEnd := time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC)
+
+	full := []string{"Roger Federer", "Andy Murray", "Rafael Nadal", "Novak Djokovic"}
+	partial := []string{"Roger Federer", "", "Rafael Nadal", "Novak Djokovic"}
+	empty := []string{"", "", "", ""}
+
+	cases := []struct {
+		name     string
+		state    string
+		slots    SlotSlice
+		now      time.Time
+		expected string
+	}{
+		{"Not published", "", lifecycleSlots(empty, ""), beforeStart, DrawStateScheduled},
+		{"Published with qualifiers pending", DrawStateScheduled, lifecycleSlots(partial, ""), beforeStart, DrawStatePublished},
+		{"Predictions open", DrawStatePublished, lifecycleSlots(full, ""), beforeStart, DrawStatePredictionsOpen},
+		{"In progress", DrawStatePredictionsOpen, lifecycleSlots(full, ""), duringDraw, DrawStateInProgress},
+		{"Champion filled", DrawStateInProgress, lifecycleSlots(full, "Rafael Nadal"), lastDay, DrawStateCompleted},
+		{"Archived after end date", DrawStateCompleted, lifecycleSlots(full, "Rafael Nadal"), dayAfterEnd, DrawStateArchived},
+		{"Archived without champion", DrawStateInProgress, lifecycleSlots(full, ""), dayAfterEnd, DrawStateArchived},
+		{"Does not move backwards", DrawStateInProgress, lifecycleSlots(empty, ""), duringDraw, DrawStateInProgress},
+	}
+
+	for _, item := range cases {
+		t.Run(item.name, func(t *testing.T) {
+			draw := lifecycleDraw
+			draw.State = item.state
+			assert.Equal(t, nextDrawState(draw, item.slots, item.now), item.expected)
+		})
+	}
+}
+
+func TestShouldScrape(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	draw := lifecycleDraw
+	assert.False(shouldScrape(draw, time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)))
+	assert.True(shouldScrape(draw, time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)))
+
+	draw.State = DrawStateInProgress
+	assert.True(shouldScrape(draw, time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)))
+
+	draw.State = DrawStateCompleted
+	assert.False(shouldScrape(draw, time.Date(2025, 1, 26, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestGetDrawsFilter(t *testing.T) {
+	filter := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filter = r.URL.Query().Get("filter")
+		w.Write([]byte(`{"items":[]}`))
+	}))
+	defer server.Close()
+	t.Setenv("BASE_URL", server.URL)
+
+	getDraws("token")
+
+	// Completed draws are selected so they can be archived, old draws aren't
+	since := time.Now().UTC().AddDate(0, 0, -drawArchiveWindowDays).Format("2006-01-02")
+	assert.Contains(t, filter, `end_date>="`+since+`"`)
+	assert.Contains(t, filter, `state!="archived"`)
+	assert.NotContains(t, filter, `state!="completed"`)
+}
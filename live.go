@@ -0,0 +1,206 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Live scores for matches in progress: games in the current set, points in the current game
+// and who is serving. They are kept in the live_score collection, separate from set_score,
+// and the record is deleted once the match is no longer live on the page.
+
+type LiveScore struct {
+	Round     int
+	Position  int
+	SetNumber int
+	Games     int
+	Points    string
+	Serving   bool
+}
+
+type LiveScoreSlice []LiveScore
+
+func (s *LiveScoreSlice) add(liveScore LiveScore) {
+	*s = append(*s, liveScore)
+}
+
+func parseATPLive(html string) LiveScoreSlice {
+	liveScores := LiveScoreSlice{}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		slog.Error("Error parsing live scores", "error", err)
+		return liveScores
+	}
+
+	roundContainers := doc.Find(".draw-content").FilterFunction(func(_ int, selection *goquery.Selection) bool {
+		return !selection.Parents().Is("template")
+	})
+
+	// Rounds and positions are counted the same way as parseATP
+	roundContainers.Each(func(i int, rc *goquery.Selection) {
+		rc.Find(".stats-item").Each(func(j int, rawSlot *goquery.Selection) {
+			current := rawSlot.Find(".score-item.live").First()
+			if current.Length() == 0 {
+				return
+			}
+
+			games, err := strconv.Atoi(trim(current.Find("span").First().Text()))
+			if err != nil {
+				games = 0
+			}
+
+			// Completed sets stop at the first "-" placeholder, the same as parseATP
+			completed := 0
+			rawSlot.Find(".score-item").Not(".live").EachWithBreak(func(_ int, set *goquery.Selection) bool {
+				gamesStr := trim(set.Find("span").First().Text())
+				if gamesStr == "" || gamesStr == "-" {
+					return false
+				}
+				completed++
+				return true
+			})
+
+			liveScores.add(LiveScore{
+				Round:     i + 1,
+				Position:  j + 1,
+				SetNumber: completed + 1,
+				Games:     games,
+				Points:    trim(rawSlot.Find(".points").First().Text()),
+				Serving:   rawSlot.Find(".serve").Length() > 0,
+			})
+		})
+	})
+
+	return liveScores
+}
+
+func parseWTALive(html string) LiveScoreSlice {
+	liveScores := LiveScoreSlice{}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		slog.Error("Error parsing live scores", "error", err)
+		return liveScores
+	}
+
+	roundContainers := doc.Find(`.tournament-draw__tab[data-event-type="LS"]`).Find(".tournament-draw__round-container")
+	roundContainers.Each(func(i int, rc *goquery.Selection) {
+		rc.Find(".match-table__row").Each(func(j int, rawSlot *goquery.Selection) {
+			current := rawSlot.Find(".match-table__score-cell.is-live").First()
+			if current.Length() == 0 {
+				return
+			}
+
+			games := 0
+			if scores := strings.Fields(current.Text()); len(scores) > 0 {
+				games, _ = strconv.Atoi(scores[0])
+			}
+
+			// Completed sets stop at the first "." placeholder, the same as parseWTA
+			completed := 0
+			rawSlot.Find(".match-table__score-cell").Not(".is-live").EachWithBreak(func(_ int, set *goquery.Selection) bool {
+				scores := strings.Fields(set.Text())
+				if len(scores) == 0 || scores[0] == "." {
+					return false
+				}
+				completed++
+				return true
+			})
+
+			liveScores.add(LiveScore{
+				Round:     i + 1,
+				Position:  j + 1,
+				SetNumber: completed + 1,
+				Games:     games,
+				Points:    trim(rawSlot.Find(".match-table__point-score").First().Text()),
+				Serving:   rawSlot.Find(".match-table__serve-indicator").Length() > 0 || rawSlot.HasClass("is-serving"),
+			})
+		})
+	})
+
+	return liveScores
+}
+
+// Live scores change every point, so they aren't journaled like the draw and set writes
+func updateLiveScores(draw DrawRecord, liveScores LiveScoreSlice, slots SlotSlice, token string) {
+	if os.Getenv("LIVE_SCORES") != "true" {
+		return
+	}
+
+	records := getLiveScores(draw.ID, token)
+	if records == nil {
+		slog.Warn("Skipping live scores, couldn't get existing records", "name", draw.Name, "event", draw.Event, "year", draw.Year)
+		return
+	}
+
+	slotIDs := make(map[SlotKey]string)
+	for _, slot := range slots {
+		slotIDs[SlotKey{Round: slot.Round, Position: slot.Position}] = slot.ID
+	}
+
+	existing := make(map[SlotKey]LiveScoreRecord)
+	for _, record := range records {
+		existing[SlotKey{Round: record.Round, Position: record.Position}] = record
+	}
+
+	live := make(map[SlotKey]bool)
+	for _, liveScore := range liveScores {
+		key := SlotKey{Round: liveScore.Round, Position: liveScore.Position}
+		slotID, ok := slotIDs[key]
+		if !ok {
+			continue
+		}
+		live[key] = true
+
+		requestData := CreateUpdateLiveScoreReq{
+			DrawID:     draw.ID,
+			DrawSlotID: slotID,
+			Round:      liveScore.Round,
+			Position:   liveScore.Position,
+			SetNumber:  liveScore.SetNumber,
+			Games:      liveScore.Games,
+			Points:     liveScore.Points,
+			Serving:    liveScore.Serving,
+		}
+
+		method := "POST"
+		record, ok := existing[key]
+		if ok {
+			current := CreateUpdateLiveScoreReq{
+				DrawID:     record.DrawID,
+				DrawSlotID: record.DrawSlotID,
+				Round:      record.Round,
+				Position:   record.Position,
+				SetNumber:  record.SetNumber,
+				Games:      record.Games,
+				Points:     record.Points,
+				Serving:    record.Serving,
+			}
+			if current == requestData {
+				continue
+			}
+			method = "PATCH"
+		}
+
+		if _, err := writeRecord(method, "live_score", record.ID, requestData, token); err != nil {
+			slog.Error("Error saving live score", "round", key.Round, "position", key.Position, "error", err)
+		}
+	}
+
+	// Matches that are no longer live have finished, the final score is in set_score
+	for key, record := range existing {
+		if live[key] {
+			continue
+		}
+		if _, err := writeRecord("DELETE", "live_score", record.ID, nil, token); err != nil {
+			slog.Error("Error clearing live score", "round", key.Round, "position", key.Position, "error", err)
+			continue
+		}
+		slog.Info("Cleared live score", "round", key.Round, "position", key.Position)
+	}
+}
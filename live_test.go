@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const atpLiveHTML = `
+<div class="draw-content">
+	<div class="stats-item">
+		<div class="name"><a>Roger Federer</a><span>(1)</span></div>
+		<div class="score-item"><span>6</span></div>
+		<div class="score-item live"><span>3</span></div>
+		<div class="serve"></div>
+		<div class="points">40</div>
+	</div>
+	<div class="stats-item">
+		<div class="name"><a>Andy Murray</a><span></span></div>
+		<div class="score-item"><span>4</span></div>
+		<div class="score-item live"><span>2</span></div>
+		<div class="points">15</div>
+	</div>
+	<div class="stats-item">
+		<div class="name"><a>Novak Djokovic</a><span>(2)</span></div>
+		<div class="score-item"><span>6</span></div>
+		<div class="score-item"><span>-</span></div>
+	</div>
+</div>`
+
+const wtaLiveHTML = `
+<div class="tournament-draw__tab" data-event-type="LS">
+	<div class="tournament-draw__round-container">
+		<div class="match-table__row is-serving">
+			<div class="match-table__player-name"><span class="match-table__player-fullname">Iga Swiatek</span></div>
+			<div class="match-table__score-cell">7 5</div>
+			<div class="match-table__score-cell">2</div>
+			<div class="match-table__score-cell is-live">1</div>
+			<div class="match-table__point-score">AD</div>
+		</div>
+		<div class="match-table__row">
+			<div class="match-table__player-name"><span class="match-table__player-fullname">Coco Gauff</span></div>
+			<div class="match-table__score-cell">6 7</div>
+			<div class="match-table__score-cell">6</div>
+			<div class="match-table__score-cell is-live">1</div>
+			<div class="match-table__point-score">40</div>
+		</div>
+	</div>
+</div>`
+
+func TestParseLive(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ATP", func(t *testing.T) {
+		liveScores := parseATPLive(atpLiveHTML)
+		assert.Equal(t, liveScores, LiveScoreSlice{
+			{Round: 1, Position: 1, SetNumber: 2, Games: 3, Points: "40", Serving: true},
+			{Round: 1, Position: 2, SetNumber: 2, Games: 2, Points: "15", Serving: false},
+		})
+
+		// The live set isn't saved as a completed set
+		slots, _ := parseATP(atpLiveHTML, DrawRecord{ID: "d"})
+		assert.Equal(t, slots[0].Sets, SetSlice{{Number: 1, Games: 6}})
+	})
+
+	t.Run("WTA", func(t *testing.T) {
+		liveScores := parseWTALive(wtaLiveHTML)
+		assert.Equal(t, liveScores, LiveScoreSlice{
+			{Round: 1, Position: 1, SetNumber: 3, Games: 1, Points: "AD", Serving: true},
+			{Round: 1, Position: 2, SetNumber: 3, Games: 1, Points: "40", Serving: false},
+		})
+
+		slots, _ := parseWTA(wtaLiveHTML, DrawRecord{ID: "d"})
+		assert.Equal(t, slots[0].Sets, SetSlice{{Number: 1, Games: 7, Tiebreak: 5}, {Number: 2, Games: 2}})
+	})
+}
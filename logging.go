@@ -0,0 +1,56 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Structured logs with the run ID and draw ID on every line. LOG_FORMAT is json or text,
+// json by default on the droplet (https BASE_URL). LOG_LEVEL is debug, info, warn or error.
+
+var (
+	baseLogger = slog.Default()
+	logRunID   string
+)
+
+func setupLogging() {
+	format := os.Getenv("LOG_FORMAT")
+	if format == "" {
+		format = "text"
+		if strings.Contains(os.Getenv("BASE_URL"), "https://") {
+			format = "json"
+		}
+	}
+
+	level := slog.LevelInfo
+	if err := level.UnmarshalText([]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		level = slog.LevelInfo
+	}
+
+	baseLogger = newLogger(os.Stderr, format, level)
+	setLogRun("")
+}
+
+func newLogger(w io.Writer, format string, level slog.Level) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	return slog.New(slog.NewTextHandler(w, options))
+}
+
+func setLogRun(runID string) {
+	logRunID = runID
+	setLogDraw("")
+}
+
+func setLogDraw(drawID string) {
+	slog.SetDefault(baseLogger.With("run_id", logRunID, "draw_id", drawID))
+}
+
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogging(t *testing.T) {
+	previous, previousRunID := baseLogger, logRunID
+	t.Cleanup(func() {
+		baseLogger = previous
+		setLogRun(previousRunID)
+	})
+
+	var buf bytes.Buffer
+	baseLogger = newLogger(&buf, "json", slog.LevelInfo)
+
+	setLogRun("run-1")
+	slog.Info("Started")
+	setLogDraw("draw-1")
+	slog.Info("Added slot", "round", 1, "position", 2)
+	slog.Debug("Hidden below info level")
+	setLogDraw("")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	entries := []map[string]any{}
+	for _, line := range lines {
+		entry := map[string]any{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+
+	assert.Equal(t, entries[0]["run_id"], "run-1")
+	assert.Equal(t, entries[0]["draw_id"], "")
+	assert.Equal(t, entries[1]["draw_id"], "draw-1")
+	assert.Equal(t, entries[1]["msg"], "Added slot")
+	assert.Equal(t, entries[1]["position"], float64(2))
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -12,64 +16,242 @@ import (
 func main() {
 	location, err := time.LoadLocation("UTC")
 	if err != nil {
-		log.Fatal(err)
+		fatal("Error loading UTC location", "error", err)
 	}
 	time.Local = location
 
-	log.SetOutput(os.Stderr)
-
 	// set environment variables for local testing
 	// on remote (https), environment variables are set before running script
+	var envErr error
 	if !strings.Contains(os.Getenv("BASE_URL"), "https://") {
-		envErr := godotenv.Load()
-		if envErr != nil {
-			log.Println("Error loading .env file,", envErr)
+		envErr = godotenv.Load()
+	}
+
+	setupLogging()
+	if envErr != nil {
+		slog.Error("Error loading .env file", "error", envErr)
+	}
+
+	command := "sync"
+	args := []string{}
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+		args = os.Args[2:]
+	}
+
+	switch command {
+	case "sync":
+		if !runSync() {
+			os.Exit(1)
 		}
+	case "rollback":
+		runRollback(args)
+	case "discover":
+		runDiscover(args)
+	case "daemon":
+		runDaemon()
+	case "replay":
+		runReplay(args)
+	default:
+		fatal("Unknown command", "command", command)
 	}
+}
 
+// Returns false when any draw failed
+func runSync() bool {
 	token := login()
-	draws := getDraws(token)
+	if token == "" {
+		slog.Error("Error logging in")
+		return false
+	}
+
+	draws, err := getDraws(token)
+	if err != nil {
+		slog.Error("Error getting draws", "error", err)
+		return false
+	}
 
 	if len(draws) == 0 {
-		printWithTimestamp("No active draws")
-		return
+		slog.Info("No active draws")
+		return true
 	}
 
 	scraper := &RealScraper{}
+	tracker := loadCorrectionTracker()
+	defer tracker.save()
+	journal := newJournal(newRunID(), token)
+	setLogRun(journal.RunID)
+	hashes := loadPageHashes()
+	alerter := loadAlerter()
+	report := newRunReport(journal.RunID, time.Now())
 
 	for _, draw := range draws {
-		currentSlots := getSlots(draw.ID, token)
-		var scrapedSlots SlotSlice
-		var seeds map[string]string
-
-		switch draw.Event {
-		case "Men's Singles":
-			scrapedSlots, seeds = scrapeATP(scraper, draw)
-		case "Women's Singles":
-			scrapedSlots, seeds = scrapeWTA(scraper, draw)
-		default:
-			log.Println("Invalid event:", draw.Event)
-			continue
+		drawReport := report.startDraw(draw)
+		draw, err := syncDraw(draw, scraper, token, tracker, hashes, journal, drawReport)
+		report.finishDraw(drawReport, draw, err)
+		alerter.record(draw, err, time.Now())
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(scraper.Served)) {
+		slog.Info("Pages served", "backend", key, "pages", scraper.Served[key])
+	}
+
+	writeMetricsTextfile()
+
+	pruneArchive(archiveDir(), time.Now())
+
+	report.finish(time.Now())
+	writeRunReport(report)
+
+	return report.Failed == 0
+}
+
+// Checks nothing is left to write, re-reading the slots if there were changes. Corrections
+// and set deletions waiting for confirmation need the same page scraped again, so they count
+// as not synced.
+func synced(scrapedSlots SlotSlice, currentSlots SlotSlice, seeds map[string]string, changed bool, drawID string, token string, tracker *CorrectionTracker) bool {
+	if tracker.hasPending(drawID) {
+		return false
+	}
+
+	if changed {
+		currentSlots = getSlots(drawID, token)
+		if currentSlots == nil {
+			return false
 		}
+	}
+
+	newSlots, updatedSlots, newSets, updatedSets, deletedSets, _ := getUpdates(scrapedSlots, currentSlots, seeds, nil)
+	return len(newSlots)+len(updatedSlots)+len(newSets)+len(updatedSets)+len(deletedSets) == 0
+}
+
+func parseDraw(html string, draw DrawRecord) (SlotSlice, map[string]string, LiveScoreSlice) {
+	if draw.Event == "Women's Singles" {
+		slots, seeds := parseWTA(html, draw)
+		return slots, seeds, parseWTALive(html)
+	}
+	slots, seeds := parseATP(html, draw)
+	return slots, seeds, parseATPLive(html)
+}
+
+// Scrapes one draw and loads the changes, returns the draw with its latest state.
+// The error is set when the draw should have synced but couldn't.
+func syncDraw(draw DrawRecord, scraper Scraper, token string, tracker *CorrectionTracker, hashes *PageHashes, journal *Journal, report *DrawReport) (DrawRecord, error) {
+	setLogDraw(draw.ID)
+	defer setLogDraw("")
 
-		received := len(scrapedSlots)
-		expected := (draw.Size * 2) - 1
-
-		if received != expected {
-			log.Printf("Incorrect number of scraped slots for %s %s %d. Expected: %d, received: %d.",
-				draw.Name,
-				draw.Event,
-				draw.Year,
-				expected,
-				received)
-			continue
+	currentSlots := getSlots(draw.ID, token)
+	journal.startDraw(draw, currentSlots)
+
+	enforcePredictionClose(draw, token, journal)
+
+	// Time based transitions, e.g. archiving after end_date, happen before scraping
+	draw = transitionDraw(draw, currentSlots, token, journal)
+	if !shouldScrape(draw, time.Now()) {
+		report.skip(DrawStatusSkipped, fmt.Sprintf("not scraped while %s", draw.State))
+		return draw, nil
+	}
+
+	if draw.Event != "Men's Singles" && draw.Event != "Women's Singles" {
+		slog.Error("Invalid event", "event", draw.Event)
+		return draw, fmt.Errorf("invalid event %q", draw.Event)
+	}
+
+	scraper = archiveScraper(scraper, draw, currentSlots)
+	html, err := fetchReadyPage(scraper, draw)
+	if err != nil {
+		slog.Error("Skipping draw", "name", draw.Name, "event", draw.Event, "year", draw.Year, "url", draw.Url, "error", err)
+		return draw, err
+	}
+
+	hash := pageHash(html)
+	if hashes.unchanged(draw.ID, hash) {
+		slog.Info("No change", "name", draw.Name, "event", draw.Event, "year", draw.Year)
+		report.skip(DrawStatusUnchanged, "page unchanged")
+		updateSchedule(scraper, draw, token, journal)
+		return transitionDraw(draw, currentSlots, token, journal), nil
+	}
+
+	scrapedSlots, seeds, liveScores := parseDraw(html, draw)
+
+	if detected := detectDrawSize(scrapedSlots); detected != draw.Size {
+		var corrected bool
+		draw, corrected = correctDrawSize(draw, scrapedSlots, token, journal)
+		if !corrected {
+			diagnostic := drawSizeDiagnostic(draw, scrapedSlots)
+			slog.Error(diagnostic)
+			return draw, errors.New(diagnostic)
 		}
+	}
 
-		newSlots, updatedSlots, newSets, updatedSets := getUpdates(scrapedSlots, currentSlots, seeds)
+	received := len(scrapedSlots)
+	expected := (draw.Size * 2) - 1
 
-		postSlots(newSlots, token)
-		updateSlots(updatedSlots, token)
-		postSets(newSets, token)
-		updateSets(updatedSets, token)
+	if received != expected {
+		slog.Error("Incorrect number of scraped slots",
+			"name", draw.Name,
+			"event", draw.Event,
+			"year", draw.Year,
+			"expected", expected,
+			"received", received)
+		return draw, fmt.Errorf("incorrect number of scraped slots, expected %d, received %d", expected, received)
 	}
+
+	newSlots, updatedSlots, newSets, updatedSets, deletedSets, corrections := getUpdates(scrapedSlots, currentSlots, seeds, tracker)
+
+	for _, conflict := range getLockConflicts(scrapedSlots, currentSlots, seeds) {
+		slog.Warn("Locked field differs from scraped", "collection", conflict.Collection, "record_id", conflict.RecordID,
+			"round", conflict.Round, "position", conflict.Position, "field", conflict.Field, "locked", conflict.Locked, "scraped", conflict.Scraped)
+	}
+
+	slotsCreated, setsCreated, postSlotsErr := postSlots(newSlots, token, journal)
+	slotsUpdated, updateSlotsErr := updateSlots(updatedSlots, token, journal)
+	written := applyCorrections(corrections, token, journal)
+	tracker.resolve(corrections, written)
+	deletedSets = withoutFailedCorrections(deletedSets, corrections, written)
+	newSets = withoutFailedCorrections(newSets, corrections, written)
+	setsDeleted, deleteSetsErr := deleteSets(deletedSets, token, journal)
+	setsPosted, postSetsErr := postSets(newSets, token, journal)
+	setsUpdated, updateSetsErr := updateSets(updatedSets, token, journal)
+
+	// Failed writes fail the draw, so they are reported and alerted like a failed scrape
+	writeErr := errors.Join(postSlotsErr, updateSlotsErr, writeFailures("corrections", len(corrections)-len(written), len(corrections)),
+		deleteSetsErr, postSetsErr, updateSetsErr)
+	setsCreated += setsPosted
+	report.recordWrites(slotsCreated, slotsUpdated, setsCreated, setsUpdated, setsDeleted, len(written))
+
+	slog.Info("Synced draw",
+		"slots_created", slotsCreated,
+		"slots_updated", slotsUpdated,
+		"sets_created", setsCreated,
+		"sets_updated", setsUpdated,
+		"sets_deleted", setsDeleted,
+		"corrections", len(written))
+	if writeErr != nil {
+		slog.Error("Error writing draw", "name", draw.Name, "event", draw.Event, "year", draw.Year, "error", writeErr)
+	}
+
+	if len(newSlots) > 0 || len(updatedSlots) > 0 || len(corrections) > 0 {
+		if updateScores(draw, token, journal) {
+			updateLeaderboards(draw, token, journal)
+		}
+	}
+
+	// Slots created this run need their IDs for live scores
+	liveSlots := currentSlots
+	if len(newSlots) > 0 {
+		if slots := getSlots(draw.ID, token); slots != nil {
+			liveSlots = slots
+		}
+	}
+	updateLiveScores(draw, liveScores, liveSlots, token)
+	updateSchedule(scraper, draw, token, journal)
+
+	// Only skip this page next time if everything was saved, including pending corrections
+	changed := len(newSlots)+len(updatedSlots)+len(newSets)+len(updatedSets)+len(deletedSets)+len(corrections) > 0
+	if writeErr == nil && synced(scrapedSlots, currentSlots, seeds, changed, draw.ID, token, tracker) {
+		hashes.set(draw.ID, hash)
+	}
+
+	return transitionDraw(draw, scrapedSlots, token, journal), writeErr
 }
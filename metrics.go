@@ -0,0 +1,123 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Counters and histograms in the Prometheus text format, served on /metrics by the daemon
+// (METRICS_ADDR, default :9090) or written to METRICS_TEXTFILE for the node exporter's
+// textfile collector after a one-shot run.
+
+type Counter struct {
+	vec        *prometheus.CounterVec
+	labelNames []string
+}
+
+type Histogram struct {
+	vec *prometheus.HistogramVec
+}
+
+func newCounter(registry *prometheus.Registry, name string, help string, labelNames ...string) *Counter {
+	vec := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labelNames)
+	registry.MustRegister(vec)
+	return &Counter{vec: vec, labelNames: labelNames}
+}
+
+func newHistogram(registry *prometheus.Registry, name string, help string, buckets []float64, labelNames ...string) *Histogram {
+	vec := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labelNames)
+	registry.MustRegister(vec)
+	return &Histogram{vec: vec}
+}
+
+func (c *Counter) add(value float64, labels ...string) {
+	c.vec.WithLabelValues(labels...).Add(value)
+}
+
+func (c *Counter) inc(labels ...string) {
+	c.add(1, labels...)
+}
+
+// Sum of the series matching the given label values, an empty value matches anything
+func (c *Counter) total(labels ...string) float64 {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		c.vec.Collect(ch)
+		close(ch)
+	}()
+
+	sum := 0.0
+	for metric := range ch {
+		series := &dto.Metric{}
+		if err := metric.Write(series); err != nil {
+			continue
+		}
+
+		values := make(map[string]string)
+		for _, pair := range series.GetLabel() {
+			values[pair.GetName()] = pair.GetValue()
+		}
+
+		matches := true
+		for i, label := range labels {
+			if label != "" && values[c.labelNames[i]] != label {
+				matches = false
+			}
+		}
+		if matches {
+			sum += series.GetCounter().GetValue()
+		}
+	}
+	return sum
+}
+
+func (h *Histogram) observe(value float64, labels ...string) {
+	h.vec.WithLabelValues(labels...).Observe(value)
+}
+
+func metricsHandler(registry *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorLog: slog.NewLogLogger(slog.Default().Handler(), slog.LevelError)})
+}
+
+// WriteToTextfile renames a temporary file into place, so the collector never reads a partial file
+func writeTextfile(registry *prometheus.Registry, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return prometheus.WriteToTextfile(path, registry)
+}
+
+func writeMetricsTextfile() {
+	path := os.Getenv("METRICS_TEXTFILE")
+	if path == "" {
+		return
+	}
+	if err := writeTextfile(metricsRegistry, path); err != nil {
+		slog.Error("Error writing metrics textfile", "path", path, "error", err)
+	}
+}
+
+var metricsRegistry = prometheus.NewRegistry()
+
+var metrics = struct {
+	scrapeDuration *Histogram
+	scrapeAttempts *Counter
+	parseWarnings  *Counter
+	slotsWritten   *Counter
+	setsWritten    *Counter
+	apiErrors      *Counter
+}{
+	scrapeDuration: newHistogram(metricsRegistry, "tennis_sync_scrape_duration_seconds", "Time to fetch a page.",
+		[]float64{1, 5, 15, 30, 60, 120, 300, 600}, "provider", "backend"),
+	scrapeAttempts: newCounter(metricsRegistry, "tennis_sync_scrape_attempts_total", "Page fetch attempts.", "provider", "backend", "result"),
+	parseWarnings:  newCounter(metricsRegistry, "tennis_sync_parse_warnings_total", "Values that couldn't be parsed from a page.", "provider"),
+	slotsWritten:   newCounter(metricsRegistry, "tennis_sync_slots_written_total", "Draw slots written to Pocketbase.", "operation"),
+	setsWritten:    newCounter(metricsRegistry, "tennis_sync_sets_written_total", "Set scores written to Pocketbase.", "operation"),
+	apiErrors:      newCounter(metricsRegistry, "tennis_sync_api_errors_total", "Failed Pocketbase requests by status.", "status"),
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	registry := prometheus.NewRegistry()
+	attempts := newCounter(registry, "attempts_total", "Attempts.", "provider", "result")
+	duration := newHistogram(registry, "duration_seconds", "Duration.", []float64{1, 5}, "provider")
+
+	attempts.inc("wta", "success")
+	attempts.inc("atp", "failure")
+	attempts.add(2, "atp", "failure")
+	duration.observe(0.5, "atp")
+	duration.observe(3, "atp")
+	duration.observe(10, "atp")
+
+	assert.Equal(t, attempts.total(), 4.0)
+	assert.Equal(t, attempts.total("", "failure"), 3.0)
+	assert.Equal(t, attempts.total("wta", "failure"), 0.0)
+
+	expected := `# HELP attempts_total Attempts.
+# TYPE attempts_total counter
+attempts_total{provider="atp",result="failure"} 3
+attempts_total{provider="wta",result="success"} 1
+# HELP duration_seconds Duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{provider="atp",le="1"} 1
+duration_seconds_bucket{provider="atp",le="5"} 2
+duration_seconds_bucket{provider="atp",le="+Inf"} 3
+duration_seconds_sum{provider="atp"} 13.5
+duration_seconds_count{provider="atp"} 3
+`
+
+	t.Run("Endpoint", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		metricsHandler(registry).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+		assert.Equal(t, recorder.Code, 200)
+		assert.Equal(t, recorder.Body.String(), expected)
+	})
+
+	t.Run("Textfile", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "textfile", "tennis_sync.prom")
+		assert.NoError(t, writeTextfile(registry, path))
+
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, string(data), expected)
+
+		// Only the final file is left behind
+		files, _ := os.ReadDir(filepath.Dir(path))
+		assert.Len(t, files, 1)
+	})
+}
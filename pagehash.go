@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Pages are hashed after removing markup that changes on every request, so an unchanged
+// draw can skip parsing and diffing. The hash is only saved once the draw has synced.
+
+var (
+	htmlComment   = regexp.MustCompile(`(?s)<!--.*?-->`)
+	isoTimestamp  = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:?\d{2})?`)
+	cacheBuster   = regexp.MustCompile(`([?&](v|t|ts|_|cb|cache)=)[^&"'\s]*`)
+	volatileAttrs = []string{"nonce", "data-nonce", "data-timestamp", "data-time", "data-request-id", "data-csrf", "csrf-token"}
+	volatileNodes = strings.Join([]string{
+		"script", "style", "noscript", "iframe", "link", "meta",
+		"time", "[class*='advert']", "[class*='ad-slot']", "[id^='google_ads']", "[data-ad]",
+	}, ",")
+)
+
+func normalizeHTML(html string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlComment.ReplaceAllString(html, "")))
+	if err != nil {
+		slog.Error("Error normalizing page", "error", err)
+		return html
+	}
+
+	doc.Find(volatileNodes).Remove()
+	doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+		for _, attr := range volatileAttrs {
+			s.RemoveAttr(attr)
+		}
+	})
+
+	normalized, err := doc.Html()
+	if err != nil {
+		slog.Error("Error normalizing page", "error", err)
+		return html
+	}
+
+	normalized = isoTimestamp.ReplaceAllString(normalized, "")
+	normalized = cacheBuster.ReplaceAllString(normalized, "$1")
+	return strings.Join(strings.Fields(normalized), " ")
+}
+
+func pageHash(html string) string {
+	sum := sha256.Sum256([]byte(normalizeHTML(html)))
+	return hex.EncodeToString(sum[:])
+}
+
+type PageHashes struct {
+	Hashes map[string]string `json:"hashes"`
+	path   string
+}
+
+func loadPageHashes() *PageHashes {
+	hashes := &PageHashes{Hashes: make(map[string]string), path: statePath("page_hashes.json")}
+
+	data, err := os.ReadFile(hashes.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return hashes
+	}
+	if err != nil {
+		slog.Error("Error reading page hashes", "error", err)
+		return hashes
+	}
+
+	if err := json.Unmarshal(data, hashes); err != nil {
+		slog.Error("Error decoding page hashes", "error", err)
+	}
+	if hashes.Hashes == nil {
+		hashes.Hashes = make(map[string]string)
+	}
+
+	return hashes
+}
+
+// Unchanged unless FORCE_SYNC=true
+func (ph *PageHashes) unchanged(drawID string, hash string) bool {
+	if ph == nil || os.Getenv("FORCE_SYNC") == "true" {
+		return false
+	}
+	return ph.Hashes[drawID] == hash
+}
+
+func (ph *PageHashes) set(drawID string, hash string) {
+	if ph == nil {
+		return
+	}
+	ph.Hashes[drawID] = hash
+
+	data, err := json.MarshalIndent(ph, "", "  ")
+	if err != nil {
+		slog.Error("Error encoding page hashes", "error", err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ph.path), 0755); err != nil {
+		slog.Error("Error creating state directory", "error", err)
+		return
+	}
+
+	if err := os.WriteFile(ph.path, data, 0644); err != nil {
+		slog.Error("Error saving page hashes", "error", err)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageHash(t *testing.T) {
+	t.Parallel()
+
+	page := `<html><head><script nonce="%s">var t = "%s";</script><style>.a{}</style></head>
+<body><!-- rendered %s --><div class="ad-slot">%s</div>
+<div class="draw-content" data-timestamp="%s"><div class="stats-item"><div class="name"><a>%s</a></div></div></div>
+<img src="/logo.png?v=%s"><span>Updated 2025-01-20T%s:00Z</span></body></html>`
+
+	render := func(volatile string, name string) string {
+		return fmt.Sprintf(page, volatile, volatile, volatile, volatile, volatile, name, volatile, volatile)
+	}
+
+	assert.Equal(t, pageHash(render("10", "Roger Federer")), pageHash(render("11", "Roger Federer")))
+	assert.NotEqual(t, pageHash(render("10", "Roger Federer")), pageHash(render("10", "Andy Murray")))
+}
+
+func TestPageHashes(t *testing.T) {
+	hashes := &PageHashes{Hashes: make(map[string]string), path: filepath.Join(t.TempDir(), "page_hashes.json")}
+
+	assert.False(t, hashes.unchanged("d1", "abc"))
+	hashes.set("d1", "abc")
+	assert.True(t, hashes.unchanged("d1", "abc"))
+	assert.False(t, hashes.unchanged("d1", "def"))
+
+	t.Setenv("FORCE_SYNC", "true")
+	assert.False(t, hashes.unchanged("d1", "abc"))
+
+	var none *PageHashes
+	assert.False(t, none.unchanged("d1", "abc"))
+}
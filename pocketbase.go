@@ -3,14 +3,18 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"time"
 )
 
+const drawFields = "id,name,event,year,url,start_date,end_date,prediction_close,size,predictions_locked,state,timezone"
+
 func makeHTTPRequest(method, url, token string, requestData interface{}) (*http.Response, error) {
 	body, err := json.Marshal(requestData)
 	if err != nil {
@@ -29,7 +33,13 @@ func makeHTTPRequest(method, url, token string, requestData interface{}) (*http.
 	req.Header.Add("Content-Type", "application/json")
 
 	client := &http.Client{Timeout: 10 * time.Second}
-	return client.Do(req)
+	res, err := client.Do(req)
+	if err != nil {
+		metrics.apiErrors.inc("error")
+	} else if res.StatusCode >= 400 {
+		metrics.apiErrors.inc(strconv.Itoa(res.StatusCode))
+	}
+	return res, err
 }
 
 func login() string {
@@ -45,7 +55,7 @@ func login() string {
 
 	res, err := makeHTTPRequest("POST", url, "", requestData)
 	if err != nil {
-		log.Println(err)
+		slog.Error("Error making request", "error", err)
 		return ""
 	}
 	defer res.Body.Close()
@@ -53,34 +63,37 @@ func login() string {
 	userAuthRes := &UserAuthRes{}
 	derr := json.NewDecoder(res.Body).Decode(userAuthRes)
 	if derr != nil {
-		log.Println(derr)
+		slog.Error("Error decoding response", "error", derr)
 		return ""
 	}
 
 	return userAuthRes.Token
 }
 
-func getDraws(token string) []DrawRecord {
-	today := time.Now().UTC().Format("2006-01-02")
-	filter := fmt.Sprintf(`(end_date>="%s"&&url!="")`, today)
+// Draws that haven't ended, plus recently ended ones so completed draws move on to archived.
+// Older draws are left alone, so a first run doesn't process every historical draw.
+func getDraws(token string) ([]DrawRecord, error) {
+	since := time.Now().UTC().AddDate(0, 0, -drawArchiveWindowDays).Format("2006-01-02")
+	filter := fmt.Sprintf(`(url!=""&&end_date>="%s"&&state!="%s"&&state!="%s")`, since, DrawStateDraft, DrawStateArchived)
 	encodedFilter := url.QueryEscape(filter)
-	pocketbaseUrl := fmt.Sprintf(`%s/api/collections/draw/records?filter=%s&fields=id,name,event,year,url,start_date,end_date,prediction_close,size`, os.Getenv("BASE_URL"), encodedFilter)
+	pocketbaseUrl := fmt.Sprintf(`%s/api/collections/draw/records?perPage=500&filter=%s&fields=%s`, os.Getenv("BASE_URL"), encodedFilter, drawFields)
 
 	res, err := makeHTTPRequest("GET", pocketbaseUrl, token, nil)
 	if err != nil {
-		log.Println(err)
-		return nil
+		return nil, err
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode >= 300 {
+		return nil, fmt.Errorf("error getting draws: %s", res.Status)
+	}
+
 	drawRes := &DrawRes{}
-	derr := json.NewDecoder(res.Body).Decode(drawRes)
-	if derr != nil {
-		log.Println(derr)
-		return nil
+	if err := json.NewDecoder(res.Body).Decode(drawRes); err != nil {
+		return nil, err
 	}
 
-	return drawRes.Items
+	return drawRes.Items, nil
 }
 
 func getSlots(drawId string, token string) SlotSlice {
@@ -88,26 +101,37 @@ func getSlots(drawId string, token string) SlotSlice {
 
 	res, err := makeHTTPRequest("GET", url, token, nil)
 	if err != nil {
-		log.Println(err)
+		slog.Error("Error making request", "error", err)
 	}
 	defer res.Body.Close()
 
 	slotRes := &SlotRes{}
 	derr := json.NewDecoder(res.Body).Decode(slotRes)
 	if derr != nil {
-		log.Println(derr)
+		slog.Error("Error decoding response", "error", derr)
 		return nil
 	}
 
 	return toSlotSlice(slotRes.Items)
 }
 
-func postSlots(slots SlotSlice, token string) {
+// Errors when any of the records couldn't be written
+func writeFailures(records string, failed int, total int) error {
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("couldn't write %d of %d %s", failed, total, records)
+}
+
+// Returns the number of slots and sets created
+func postSlots(slots SlotSlice, token string, journal *Journal) (int, int, error) {
 	if len(slots) == 0 {
-		return
+		return 0, 0, nil
 	}
 
 	url := fmt.Sprintf(`%s/api/collections/draw_slot/records`, os.Getenv("BASE_URL"))
+	created, setsCreated := 0, 0
+	errs := []error{}
 
 	for _, slot := range slots {
 		requestData := CreateUpdateSlotReq{
@@ -119,7 +143,8 @@ func postSlots(slots SlotSlice, token string) {
 		}
 		res, err := makeHTTPRequest("POST", url, token, requestData)
 		if err != nil {
-			log.Println(err)
+			slog.Error("Error making request", "error", err)
+			continue
 		}
 		defer res.Body.Close()
 
@@ -131,9 +156,18 @@ func postSlots(slots SlotSlice, token string) {
 		// Decode the JSON response
 		err = json.NewDecoder(res.Body).Decode(&responseData)
 		if err != nil {
-			log.Println("Error decoding response:", err)
+			slog.Error("Error decoding response", "error", err)
+			continue
+		}
+
+		slog.Info("Added slot", "status", res.Status, "slot_id", responseData.ID, "round", slot.Round, "position", slot.Position, "name", slot.Name, "seed", slot.Seed)
+
+		if res.StatusCode >= 300 {
 			continue
 		}
+		journal.recordSlot("create", responseData.ID, nil, &slot)
+		metrics.slotsWritten.inc("created")
+		created++
 
 		// Update each set's DrawSlotID with the new slot ID
 		for i := range slot.Sets {
@@ -141,16 +175,20 @@ func postSlots(slots SlotSlice, token string) {
 		}
 
 		// Post sets for the new slot
-		postSets(slot.Sets, token)
-
-		printWithTimestamp(res.Status, "added slot", slot)
+		sets, err := postSets(slot.Sets, token, journal)
+		setsCreated += sets
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
+
+	errs = append(errs, writeFailures("new slots", len(slots)-created, len(slots)))
+	return created, setsCreated, errors.Join(errs...)
 }
 
-func updateSlots(slots SlotSlice, token string) {
-	if len(slots) == 0 {
-		return
-	}
+// Returns the number of slots updated
+func updateSlots(slots SlotSlice, token string, journal *Journal) (int, error) {
+	updated := 0
 
 	for _, slot := range slots {
 		url := fmt.Sprintf(`%s/api/collections/draw_slot/records/%s`, os.Getenv("BASE_URL"), slot.ID)
@@ -163,22 +201,34 @@ func updateSlots(slots SlotSlice, token string) {
 		}
 		res, err := makeHTTPRequest("PATCH", url, token, requestData)
 		if err != nil {
-			log.Println(err)
+			slog.Error("Error making request", "error", err)
+			continue
 		}
 		defer res.Body.Close()
 
-		printWithTimestamp(res.Status, "updated slot", slot)
-	}
-}
+		if res.StatusCode < 300 {
+			journal.recordSlot("update", slot.ID, nil, &slot)
+			metrics.slotsWritten.inc("updated")
+			updated++
+		}
 
-func postSets(setScores SetSlice, token string) {
-	if len(setScores) == 0 {
-		return
+		slog.Info("Updated slot", "status", res.Status, "slot_id", slot.ID, "round", slot.Round, "position", slot.Position, "name", slot.Name, "seed", slot.Seed)
 	}
 
+	return updated, writeFailures("slot updates", len(slots)-updated, len(slots))
+}
+
+// Returns the number of sets created. Sets of new slots are posted with their slot, see postSlots.
+func postSets(setScores SetSlice, token string, journal *Journal) (int, error) {
 	url := fmt.Sprintf(`%s/api/collections/set_score/records`, os.Getenv("BASE_URL"))
+	created, total := 0, 0
 
 	for _, setScore := range setScores {
+		if setScore.DrawSlotID == "" {
+			continue
+		}
+		total++
+
 		requestData := CreateUpdateSetReq{
 			DrawSlotID: setScore.DrawSlotID,
 			Number:     setScore.Number,
@@ -187,18 +237,35 @@ func postSets(setScores SetSlice, token string) {
 		}
 		res, err := makeHTTPRequest("POST", url, token, requestData)
 		if err != nil {
-			log.Println(err)
+			slog.Error("Error making request", "error", err)
+			continue
 		}
 		defer res.Body.Close()
 
-		printWithTimestamp(res.Status, "added set", setScore)
+		var responseData struct {
+			ID string `json:"id"`
+		}
+
+		err = json.NewDecoder(res.Body).Decode(&responseData)
+		if err != nil {
+			slog.Error("Error decoding response", "error", err)
+		}
+
+		if res.StatusCode < 300 {
+			journal.recordSet("create", responseData.ID, nil, &setScore)
+			metrics.setsWritten.inc("created")
+			created++
+		}
+
+		slog.Info("Added set", "status", res.Status, "set_id", responseData.ID, "slot_id", setScore.DrawSlotID, "set", setScore.Number, "games", setScore.Games, "tiebreak", setScore.Tiebreak)
 	}
+
+	return created, writeFailures("new sets", total-created, total)
 }
 
-func updateSets(setScores SetSlice, token string) {
-	if len(setScores) == 0 {
-		return
-	}
+// Returns the number of sets updated
+func updateSets(setScores SetSlice, token string, journal *Journal) (int, error) {
+	updated := 0
 
 	for _, setScore := range setScores {
 		url := fmt.Sprintf(`%s/api/collections/set_score/records/%s`, os.Getenv("BASE_URL"), setScore.ID)
@@ -210,10 +277,336 @@ func updateSets(setScores SetSlice, token string) {
 		}
 		res, err := makeHTTPRequest("PATCH", url, token, requestData)
 		if err != nil {
-			log.Println(err)
+			slog.Error("Error making request", "error", err)
+			continue
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode < 300 {
+			journal.recordSet("update", setScore.ID, nil, &setScore)
+			metrics.setsWritten.inc("updated")
+			updated++
+		}
+
+		slog.Info("Updated set", "status", res.Status, "set_id", setScore.ID, "slot_id", setScore.DrawSlotID, "set", setScore.Number, "games", setScore.Games, "tiebreak", setScore.Tiebreak)
+	}
+
+	return updated, writeFailures("set updates", len(setScores)-updated, len(setScores))
+}
+
+// Returns the IDs of the slots that were written
+func applyCorrections(corrections CorrectionSlice, token string, journal *Journal) map[string]bool {
+	written := make(map[string]bool)
+
+	for _, correction := range corrections {
+		slot := correction.After
+		url := fmt.Sprintf(`%s/api/collections/draw_slot/records/%s`, os.Getenv("BASE_URL"), slot.ID)
+		requestData := CreateUpdateSlotReq{
+			DrawID:   slot.DrawID,
+			Round:    slot.Round,
+			Position: slot.Position,
+			Name:     slot.Name,
+			Seed:     slot.Seed,
+		}
+		res, err := makeHTTPRequest("PATCH", url, token, requestData)
+		if err != nil {
+			slog.Error("Error making request", "error", err)
+			recordCorrection(correction, "failed")
+			continue
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode < 300 {
+			journal.recordSlot("update", slot.ID, &correction.Before, &slot)
+			metrics.slotsWritten.inc("corrected")
+			written[slot.ID] = true
+		}
+
+		slog.Info("Corrected slot", "status", res.Status, "slot_id", slot.ID, "round", slot.Round, "position", slot.Position, "before", correction.Before.Name, "after", slot.Name, "confirmations", correction.Confirmations)
+		recordCorrection(correction, res.Status)
+	}
+
+	return written
+}
+
+// Returns the number of sets deleted
+func deleteSets(setScores SetSlice, token string, journal *Journal) (int, error) {
+	deleted := 0
+
+	for _, setScore := range setScores {
+		url := fmt.Sprintf(`%s/api/collections/set_score/records/%s`, os.Getenv("BASE_URL"), setScore.ID)
+		res, err := makeHTTPRequest("DELETE", url, token, nil)
+		if err != nil {
+			slog.Error("Error making request", "error", err)
+			continue
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode < 300 {
+			journal.recordSet("delete", setScore.ID, &setScore, nil)
+			metrics.setsWritten.inc("deleted")
+			deleted++
+		}
+
+		slog.Info("Deleted set", "status", res.Status, "set_id", setScore.ID, "slot_id", setScore.DrawSlotID, "set", setScore.Number)
+	}
+
+	return deleted, writeFailures("set deletions", len(setScores)-deleted, len(setScores))
+}
+
+func postSyncLog(entry JournalEntry, token string) {
+	url := fmt.Sprintf(`%s/api/collections/sync_log/records`, os.Getenv("BASE_URL"))
+
+	res, err := makeHTTPRequest("POST", url, token, entry)
+	if err != nil {
+		slog.Error("Error posting sync log", "error", err)
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		slog.Error("Error posting sync log", "status", res.Status)
+	}
+}
+
+// Returns nil without an error when the record doesn't exist
+func getRecord(collection string, id string, token string) (map[string]any, error) {
+	url := fmt.Sprintf(`%s/api/collections/%s/records/%s`, os.Getenv("BASE_URL"), collection, id)
+
+	res, err := makeHTTPRequest("GET", url, token, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.StatusCode >= 300 {
+		return nil, fmt.Errorf("error getting %s record %s: %s", collection, id, res.Status)
+	}
+
+	record := make(map[string]any)
+	if err := json.NewDecoder(res.Body).Decode(&record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// Writes a raw payload to a record and returns its ID, used for records without a typed helper
+func writeRecord(method string, collection string, id string, payload any, token string) (string, error) {
+	url := fmt.Sprintf(`%s/api/collections/%s/records`, os.Getenv("BASE_URL"), collection)
+	if method != "POST" {
+		url = fmt.Sprintf(`%s/%s`, url, id)
+	}
+
+	res, err := makeHTTPRequest(method, url, token, payload)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return "", fmt.Errorf("error writing %s record %s: %s", collection, id, res.Status)
+	}
+
+	if method == "DELETE" {
+		return id, nil
+	}
+
+	var responseData struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&responseData); err != nil {
+		return "", err
+	}
+
+	return responseData.ID, nil
+}
+
+func getPredictions(drawId string, token string) []PredictionRecord {
+	predictions := []PredictionRecord{}
+
+	for page := 1; ; page++ {
+		url := fmt.Sprintf(`%s/api/collections/prediction/records?page=%d&perPage=500&filter=(draw_id="%s")`, os.Getenv("BASE_URL"), page, drawId)
+
+		res, err := makeHTTPRequest("GET", url, token, nil)
+		if err != nil {
+			slog.Error("Error making request", "error", err)
+			return nil
+		}
+		defer res.Body.Close()
+
+		predictionRes := &PredictionRes{}
+		derr := json.NewDecoder(res.Body).Decode(predictionRes)
+		if derr != nil {
+			slog.Error("Error decoding response", "error", derr)
+			return nil
+		}
+
+		predictions = append(predictions, predictionRes.Items...)
+
+		if page >= predictionRes.TotalPages {
+			return predictions
+		}
+	}
+}
+
+func getScores(drawId string, token string) []ScoreRecord {
+	scores := []ScoreRecord{}
+
+	for page := 1; ; page++ {
+		url := fmt.Sprintf(`%s/api/collections/score/records?page=%d&perPage=500&filter=(draw_id="%s")`, os.Getenv("BASE_URL"), page, drawId)
+
+		res, err := makeHTTPRequest("GET", url, token, nil)
+		if err != nil {
+			slog.Error("Error making request", "error", err)
+			return nil
 		}
 		defer res.Body.Close()
 
-		printWithTimestamp(res.Status, "updated set", setScore)
+		scoreRes := &ScoreRes{}
+		derr := json.NewDecoder(res.Body).Decode(scoreRes)
+		if derr != nil {
+			slog.Error("Error decoding response", "error", derr)
+			return nil
+		}
+
+		scores = append(scores, scoreRes.Items...)
+
+		if page >= scoreRes.TotalPages {
+			return scores
+		}
 	}
 }
+
+func getDrawsByYear(year int, token string) []DrawRecord {
+	filter := url.QueryEscape(fmt.Sprintf(`(year=%d)`, year))
+	pocketbaseUrl := fmt.Sprintf(`%s/api/collections/draw/records?perPage=500&filter=%s&fields=%s&skipTotal=true`, os.Getenv("BASE_URL"), filter, drawFields)
+
+	res, err := makeHTTPRequest("GET", pocketbaseUrl, token, nil)
+	if err != nil {
+		slog.Error("Error making request", "error", err)
+		return nil
+	}
+	defer res.Body.Close()
+
+	drawRes := &DrawRes{}
+	derr := json.NewDecoder(res.Body).Decode(drawRes)
+	if derr != nil {
+		slog.Error("Error decoding response", "error", derr)
+		return nil
+	}
+
+	return drawRes.Items
+}
+
+func getLeaderboard(filter string, token string) []LeaderboardRecord {
+	records := []LeaderboardRecord{}
+
+	for page := 1; ; page++ {
+		pocketbaseUrl := fmt.Sprintf(`%s/api/collections/leaderboard/records?page=%d&perPage=500&filter=%s`, os.Getenv("BASE_URL"), page, url.QueryEscape(filter))
+
+		res, err := makeHTTPRequest("GET", pocketbaseUrl, token, nil)
+		if err != nil {
+			slog.Error("Error making request", "error", err)
+			return nil
+		}
+		defer res.Body.Close()
+
+		leaderboardRes := &LeaderboardRes{}
+		derr := json.NewDecoder(res.Body).Decode(leaderboardRes)
+		if derr != nil {
+			slog.Error("Error decoding response", "error", derr)
+			return nil
+		}
+
+		records = append(records, leaderboardRes.Items...)
+
+		if page >= leaderboardRes.TotalPages {
+			return records
+		}
+	}
+}
+
+// Updates the draw size to match the page when AUTO_CORRECT_DRAW_SIZE is set
+// and the page is a complete draw
+func correctDrawSize(draw DrawRecord, slots SlotSlice, token string, journal *Journal) (DrawRecord, bool) {
+	detected := detectDrawSize(slots)
+	if os.Getenv("AUTO_CORRECT_DRAW_SIZE") != "true" || !isPowerOfTwo(detected) || len(slots) != detected*2-1 {
+		return draw, false
+	}
+
+	update := map[string]int{"size": detected}
+	if _, err := writeRecord("PATCH", "draw", draw.ID, update, token); err != nil {
+		slog.Error("Error correcting draw size", "error", err)
+		return draw, false
+	}
+	journal.record("update", "draw", draw.ID, toRawJSON(map[string]int{"size": draw.Size}), toRawJSON(update))
+
+	slog.Info("Corrected draw size", "name", draw.Name, "event", draw.Event, "year", draw.Year, "from", draw.Size, "to", detected)
+	draw.Size = detected
+	return draw, true
+}
+
+func getMatchSchedules(drawId string, token string) []MatchScheduleRecord {
+	url := fmt.Sprintf(`%s/api/collections/match_schedule/records?perPage=500&filter=(draw_id="%s")&skipTotal=true`, os.Getenv("BASE_URL"), drawId)
+
+	res, err := makeHTTPRequest("GET", url, token, nil)
+	if err != nil {
+		slog.Error("Error making request", "error", err)
+		return nil
+	}
+	defer res.Body.Close()
+
+	scheduleRes := &MatchScheduleRes{}
+	derr := json.NewDecoder(res.Body).Decode(scheduleRes)
+	if derr != nil {
+		slog.Error("Error decoding response", "error", derr)
+		return nil
+	}
+
+	return scheduleRes.Items
+}
+
+func getLiveScores(drawId string, token string) []LiveScoreRecord {
+	url := fmt.Sprintf(`%s/api/collections/live_score/records?perPage=500&filter=(draw_id="%s")&skipTotal=true`, os.Getenv("BASE_URL"), drawId)
+
+	res, err := makeHTTPRequest("GET", url, token, nil)
+	if err != nil {
+		slog.Error("Error making request", "error", err)
+		return nil
+	}
+	defer res.Body.Close()
+
+	liveScoreRes := &LiveScoreRes{}
+	derr := json.NewDecoder(res.Body).Decode(liveScoreRes)
+	if derr != nil {
+		slog.Error("Error decoding response", "error", derr)
+		return nil
+	}
+
+	return liveScoreRes.Items
+}
+
+func getDraw(drawId string, token string) (DrawRecord, error) {
+	url := fmt.Sprintf(`%s/api/collections/draw/records/%s?fields=%s`, os.Getenv("BASE_URL"), drawId, drawFields)
+
+	res, err := makeHTTPRequest("GET", url, token, nil)
+	if err != nil {
+		return DrawRecord{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return DrawRecord{}, fmt.Errorf("error getting draw %s: %s", drawId, res.Status)
+	}
+
+	draw := DrawRecord{}
+	if err := json.NewDecoder(res.Body).Decode(&draw); err != nil {
+		return DrawRecord{}, err
+	}
+
+	return draw, nil
+}
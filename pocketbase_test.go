@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWritesCountFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/bad") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id": "new"})
+	}))
+	defer server.Close()
+	t.Setenv("BASE_URL", server.URL)
+	assert := assert.New(t)
+
+	updated, err := updateSlots(SlotSlice{{ID: "aaa"}, {ID: "bad"}}, "token", nil)
+	assert.Equal(updated, 1)
+	assert.EqualError(err, "couldn't write 1 of 2 slot updates")
+
+	deleted, err := deleteSets(SetSlice{{ID: "ss_a_1"}}, "token", nil)
+	assert.Equal(deleted, 1)
+	assert.NoError(err)
+
+	// Sets of new slots are posted with the slot
+	created, setsCreated, err := postSlots(SlotSlice{{Round: 1, Position: 1, Sets: SetSlice{{Number: 1, Games: 6}}}}, "token", nil)
+	assert.Equal(created, 1)
+	assert.Equal(setsCreated, 1)
+	assert.NoError(err)
+
+	created, err = postSets(SetSlice{{Number: 1, Games: 6}}, "token", nil)
+	assert.Equal(created, 0)
+	assert.NoError(err)
+}
+
+func TestGetDrawsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"items":[]}`))
+	}))
+	defer server.Close()
+	t.Setenv("BASE_URL", server.URL)
+
+	draws, err := getDraws("expired")
+	assert.Nil(t, draws)
+	assert.EqualError(t, err, "error getting draws: 401 Unauthorized")
+}
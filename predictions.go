@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// Once prediction_close passes, predictions for the draw are snapshotted with a hash and the
+// draw is locked. The prediction_snapshot collection's API rules should not allow updates or
+// deletes, so the snapshot can be used to settle disputes.
+
+func predictionsClosed(draw DrawRecord, now time.Time) bool {
+	deadline, err := drawTime(draw.Prediction_Close, drawLocation(draw))
+	if err != nil {
+		return false
+	}
+	return !now.Before(deadline)
+}
+
+// Predictions created or modified after the deadline don't count
+func filterPredictionsBeforeClose(predictions []PredictionRecord, draw DrawRecord) []PredictionRecord {
+	deadline, err := drawTime(draw.Prediction_Close, drawLocation(draw))
+	if err != nil {
+		slog.Warn("Invalid prediction close", "name", draw.Name, "event", draw.Event, "year", draw.Year, "error", err)
+		return predictions
+	}
+
+	result := []PredictionRecord{}
+	for _, prediction := range predictions {
+		created, createdErr := parsePocketbaseTime(prediction.Created)
+		updated, updatedErr := parsePocketbaseTime(prediction.Updated)
+		if createdErr != nil || updatedErr != nil || created.After(deadline) || updated.After(deadline) {
+			continue
+		}
+		result = append(result, prediction)
+	}
+
+	return result
+}
+
+// Sorted so the same predictions always produce the same hash
+func hashPredictions(predictions []PredictionRecord) ([]PredictionRecord, string) {
+	sorted := append([]PredictionRecord{}, predictions...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].UserID != sorted[j].UserID {
+			return sorted[i].UserID < sorted[j].UserID
+		}
+		if sorted[i].Round != sorted[j].Round {
+			return sorted[i].Round < sorted[j].Round
+		}
+		return sorted[i].Position < sorted[j].Position
+	})
+
+	data, err := json.Marshal(sorted)
+	if err != nil {
+		slog.Error("Error encoding predictions", "error", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return sorted, hex.EncodeToString(sum[:])
+}
+
+func enforcePredictionClose(draw DrawRecord, token string, journal *Journal) {
+	if draw.Predictions_Locked || !predictionsClosed(draw, time.Now()) {
+		return
+	}
+
+	predictions := getPredictions(draw.ID, token)
+	if predictions == nil {
+		slog.Error("Error getting predictions to lock", "name", draw.Name, "event", draw.Event, "year", draw.Year)
+		return
+	}
+
+	valid := filterPredictionsBeforeClose(predictions, draw)
+	late := latePredictions(predictions, valid)
+
+	sorted, hash := hashPredictions(valid)
+	sortedLate, _ := hashPredictions(late)
+	snapshot := PredictionSnapshotReq{
+		DrawID:      draw.ID,
+		Hash:        hash,
+		Count:       len(sorted),
+		Predictions: sorted,
+		LateCount:   len(sortedLate),
+		Late:        sortedLate,
+		TakenAt:     time.Now().UTC().Format(time.RFC3339),
+	}
+
+	// Lock only after the snapshot is saved, so a failure is retried next run
+	id, err := writeRecord("POST", "prediction_snapshot", "", snapshot, token)
+	if err != nil {
+		slog.Error("Error saving prediction snapshot", "error", err)
+		return
+	}
+	journal.record("create", "prediction_snapshot", id, toRawJSON(nil), toRawJSON(struct {
+		Hash  string `json:"hash"`
+		Count int    `json:"count"`
+	}{hash, len(sorted)}))
+
+	lock := map[string]bool{"predictions_locked": true}
+	if _, err := writeRecord("PATCH", "draw", draw.ID, lock, token); err != nil {
+		slog.Error("Error locking predictions", "error", err)
+		return
+	}
+	journal.record("update", "draw", draw.ID, toRawJSON(map[string]bool{"predictions_locked": false}), toRawJSON(lock))
+
+	if len(sortedLate) > 0 {
+		slog.Warn("Predictions made after close", "name", draw.Name, "event", draw.Event, "year", draw.Year, "late", len(sortedLate))
+	}
+	slog.Info("Locked predictions", "name", draw.Name, "event", draw.Event, "year", draw.Year, "predictions", len(sorted), "hash", hash)
+}
+
+// Predictions dropped by filterPredictionsBeforeClose
+func latePredictions(predictions []PredictionRecord, valid []PredictionRecord) []PredictionRecord {
+	kept := make(map[string]bool)
+	for _, prediction := range valid {
+		kept[prediction.ID] = true
+	}
+
+	late := []PredictionRecord{}
+	for _, prediction := range predictions {
+		if !kept[prediction.ID] {
+			late = append(late, prediction)
+		}
+	}
+	return late
+}
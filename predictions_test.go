@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPredictionClose(t *testing.T) {
+	t.Parallel()
+
+	draw := DrawRecord{Name: "Australian Open", Prediction_Close: "2025-01-19 12:00:00.000Z"}
+
+	t.Run("Closed at deadline", func(t *testing.T) {
+		assert := assert.New(t)
+		assert.False(predictionsClosed(draw, time.Date(2025, 1, 19, 11, 59, 59, 0, time.UTC)))
+		assert.True(predictionsClosed(draw, time.Date(2025, 1, 19, 12, 0, 0, 0, time.UTC)))
+		assert.False(predictionsClosed(DrawRecord{}, time.Date(2025, 1, 19, 12, 0, 0, 0, time.UTC)))
+	})
+
+	t.Run("Late predictions filtered", func(t *testing.T) {
+		predictions := []PredictionRecord{
+			{ID: "on_time", Created: "2025-01-18 08:00:00.000Z", Updated: "2025-01-19 11:00:00.000Z"},
+			{ID: "late_create", Created: "2025-01-19 12:00:01.000Z", Updated: "2025-01-19 12:00:01.000Z"},
+			{ID: "late_update", Created: "2025-01-18 08:00:00.000Z", Updated: "2025-01-20 09:00:00.000Z"},
+			{ID: "at_deadline", Created: "2025-01-19 12:00:00.000Z", Updated: "2025-01-19 12:00:00.000Z"},
+		}
+
+		filtered := filterPredictionsBeforeClose(predictions, draw)
+		assert.Equal(t, filtered, []PredictionRecord{predictions[0], predictions[3]})
+		assert.Equal(t, latePredictions(predictions, filtered), []PredictionRecord{predictions[1], predictions[2]})
+	})
+}
+
+func TestEnforcePredictionClose(t *testing.T) {
+	var snapshot PredictionSnapshotReq
+	locked := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/prediction/records"):
+			json.NewEncoder(w).Encode(PredictionRes{TotalPages: 1, Items: []PredictionRecord{
+				{ID: "on_time", UserID: "u1", Round: 2, Position: 1, Created: "2025-01-18 08:00:00.000Z", Updated: "2025-01-18 08:00:00.000Z"},
+				{ID: "late", UserID: "u2", Round: 2, Position: 1, Created: "2025-01-18 08:00:00.000Z", Updated: "2025-01-20 09:00:00.000Z"},
+			}})
+		case strings.HasSuffix(r.URL.Path, "/prediction_snapshot/records"):
+			json.NewDecoder(r.Body).Decode(&snapshot)
+			w.Write([]byte(`{"id":"snap1"}`))
+		case strings.HasSuffix(r.URL.Path, "/draw/records/draw1"):
+			locked = true
+			w.Write([]byte(`{"id":"draw1"}`))
+		}
+	}))
+	defer server.Close()
+	t.Setenv("BASE_URL", server.URL)
+
+	draw := DrawRecord{ID: "draw1", Name: "Australian Open", Prediction_Close: "2025-01-19 12:00:00.000Z"}
+	enforcePredictionClose(draw, "token", nil)
+
+	assert.True(t, locked)
+	assert.Equal(t, snapshot.Count, 1)
+	assert.Equal(t, snapshot.Predictions[0].ID, "on_time")
+	assert.Equal(t, snapshot.LateCount, 1)
+	assert.Equal(t, snapshot.Late[0].ID, "late")
+}
+
+func TestHashPredictions(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	a := PredictionRecord{ID: "p1", UserID: "user1", Round: 2, Position: 1, Name: "Roger Federer"}
+	b := PredictionRecord{ID: "p2", UserID: "user1", Round: 2, Position: 2, Name: "Rafael Nadal"}
+	c := PredictionRecord{ID: "p3", UserID: "user2", Round: 2, Position: 1, Name: "Andy Murray"}
+
+	sorted, hash := hashPredictions([]PredictionRecord{c, b, a})
+	_, sameHash := hashPredictions([]PredictionRecord{a, b, c})
+	_, otherHash := hashPredictions([]PredictionRecord{a, b})
+
+	assert.Equal(sorted, []PredictionRecord{a, b, c})
+	assert.Equal(hash, sameHash)
+	assert.NotEqual(hash, otherHash)
+	assert.Len(hash, 64)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Readiness assertions catch partly rendered draw pages and bot challenges before parsing,
+// so the page is fetched again instead of failing the slot count check.
+
+type PageAssertions struct {
+	// Selectors that must be on the page
+	Selectors []string
+	// Round columns, not counting the champion column
+	Rounds string
+	// Slots within a round column
+	Slots string
+}
+
+var pageAssertions = map[string]PageAssertions{
+	"atp": {
+		Selectors: []string{".draw-content", ".draw-content .stats-item .name"},
+		Rounds:    ".draw-content",
+		Slots:     ".stats-item",
+	},
+	"wta": {
+		Selectors: []string{`.tournament-draw__tab[data-event-type="LS"]`, `.tournament-draw__tab[data-event-type="LS"] .match-table__row`},
+		Rounds:    `.tournament-draw__tab[data-event-type="LS"] .tournament-draw__round-container`,
+		Slots:     ".match-table__row",
+	},
+}
+
+var captchaSelectors = []string{
+	"#challenge-form",
+	"#challenge-running",
+	"#px-captcha",
+	".g-recaptcha",
+	".h-captcha",
+	"iframe[src*='captcha']",
+	"iframe[src*='challenges.cloudflare.com']",
+}
+
+var captchaTitles = []string{"just a moment", "access denied", "attention required"}
+
+type IncompletePageError struct {
+	URL     string
+	Reasons []string
+}
+
+func (e *IncompletePageError) Error() string {
+	return fmt.Sprintf("incomplete page %s: %s", e.URL, strings.Join(e.Reasons, "; "))
+}
+
+func checkPageReady(html string, draw DrawRecord) error {
+	assertions, ok := pageAssertions[providerFor(draw.Url)]
+	if !ok {
+		return nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return err
+	}
+
+	reasons := []string{}
+
+	for _, selector := range captchaSelectors {
+		if doc.Find(selector).Length() > 0 {
+			reasons = append(reasons, "bot challenge "+selector)
+		}
+	}
+	title := strings.ToLower(trim(doc.Find("title").First().Text()))
+	for _, marker := range captchaTitles {
+		if strings.Contains(title, marker) {
+			reasons = append(reasons, fmt.Sprintf("bot challenge title %q", title))
+		}
+	}
+
+	for _, selector := range assertions.Selectors {
+		if doc.Find(selector).Length() == 0 {
+			reasons = append(reasons, "missing "+selector)
+		}
+	}
+
+	// Round columns plus the champion column, e.g. 8 for a 128 draw
+	rounds := doc.Find(assertions.Rounds).FilterFunction(func(_ int, selection *goquery.Selection) bool {
+		return !selection.Parents().Is("template")
+	})
+	columns := rounds.Length() + 1
+	firstRound := rounds.First().Find(assertions.Slots).Length()
+
+	// A complete page for a different draw size is left to the draw size check
+	differentSize := isPowerOfTwo(firstRound) && championRound(firstRound) == columns
+	if columns != championRound(draw.Size) && !differentSize {
+		reasons = append(reasons, fmt.Sprintf("%d round columns, expected %d", columns, championRound(draw.Size)))
+	}
+
+	if len(reasons) > 0 {
+		return &IncompletePageError{URL: draw.Url, Reasons: reasons}
+	}
+	return nil
+}
+
+// Fetches the draw page again while it's incomplete, up to INCOMPLETE_PAGE_REFETCHES times (default 2)
+func fetchReadyPage(scraper Scraper, draw DrawRecord) (string, error) {
+	refetches, err := strconv.Atoi(os.Getenv("INCOMPLETE_PAGE_REFETCHES"))
+	if err != nil || refetches < 0 {
+		refetches = 2
+	}
+
+	for i := 0; ; i++ {
+		// Every backend has already been tried, so an empty page isn't fetched again
+		html := scraper.scrape(draw.Url)
+		if html == "" {
+			return "", fmt.Errorf("couldn't fetch %s", draw.Url)
+		}
+
+		err := checkPageReady(html, draw)
+
+		var incomplete *IncompletePageError
+		if errors.As(err, &incomplete) && i < refetches {
+			slog.Warn("Incomplete page, fetching again", "url", draw.Url, "attempt", i+1, "error", err)
+			continue
+		}
+		return html, err
+	}
+}
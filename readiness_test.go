@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const atpReadyHTML = `<html><head><title>Draws</title></head><body>
+<div class="draw-content">
+	<div class="stats-item"><div class="name"><a>A</a></div></div>
+	<div class="stats-item"><div class="name"><a>B</a></div></div>
+	<div class="stats-item"><div class="name"><a>C</a></div></div>
+	<div class="stats-item"><div class="name"><a>D</a></div></div>
+</div>
+<div class="draw-content">
+	<div class="stats-item"><div class="name"><a>A</a></div></div>
+	<div class="stats-item"><div class="name"><a>C</a></div></div>
+</div>
+</body></html>`
+
+const atpPartialHTML = `<html><body>
+<div class="draw-content">
+	<div class="stats-item"><div class="name"><a>A</a></div></div>
+	<div class="stats-item"><div class="name"><a>B</a></div></div>
+	<div class="stats-item"><div class="name"><a>C</a></div></div>
+	<div class="stats-item"><div class="name"><a>D</a></div></div>
+</div>
+</body></html>`
+
+const challengeHTML = `<html><head><title>Just a moment...</title></head><body><form id="challenge-form"></form></body></html>`
+
+const wtaReadyHTML = `<div class="tournament-draw__tab" data-event-type="LS">
+	<div class="tournament-draw__round-container"><div class="match-table__row"></div><div class="match-table__row"></div></div>
+</div>`
+
+type sequenceScraper struct {
+	pages []string
+	calls int
+}
+
+func (s *sequenceScraper) scrape(targetURL string) string {
+	page := s.pages[min(s.calls, len(s.pages)-1)]
+	s.calls++
+	return page
+}
+
+func TestCheckPageReady(t *testing.T) {
+	t.Parallel()
+
+	atpDraw := DrawRecord{Url: "https://www.atptour.com/en/scores/current/australian-open/580/draws", Size: 4}
+	wtaDraw := DrawRecord{Url: "https://www.wtatennis.com/tournaments/901/australian-open/2025/draws", Size: 2}
+
+	assert.NoError(t, checkPageReady(atpReadyHTML, atpDraw))
+	assert.NoError(t, checkPageReady(wtaReadyHTML, wtaDraw))
+
+	var incomplete *IncompletePageError
+
+	err := checkPageReady(atpPartialHTML, atpDraw)
+	assert.True(t, errors.As(err, &incomplete))
+	assert.Equal(t, incomplete.Reasons, []string{"2 round columns, expected 3"})
+
+	err = checkPageReady(challengeHTML, atpDraw)
+	assert.True(t, errors.As(err, &incomplete))
+	assert.Contains(t, incomplete.Reasons, "bot challenge #challenge-form")
+	assert.Contains(t, incomplete.Reasons, "missing .draw-content")
+
+	// A complete page for another size is a draw size mismatch, not an incomplete page
+	assert.NoError(t, checkPageReady(atpReadyHTML, DrawRecord{Url: atpDraw.Url, Size: 8}))
+}
+
+func TestFetchReadyPage(t *testing.T) {
+	t.Parallel()
+
+	draw := DrawRecord{Url: "https://www.atptour.com/en/scores/current/australian-open/580/draws", Size: 4}
+
+	scraper := &sequenceScraper{pages: []string{challengeHTML, atpPartialHTML, atpReadyHTML}}
+	html, err := fetchReadyPage(scraper, draw)
+	assert.NoError(t, err)
+	assert.Equal(t, html, atpReadyHTML)
+	assert.Equal(t, scraper.calls, 3)
+
+	// Gives up after the default 2 refetches
+	scraper = &sequenceScraper{pages: []string{challengeHTML}}
+	_, err = fetchReadyPage(scraper, draw)
+	var incomplete *IncompletePageError
+	assert.True(t, errors.As(err, &incomplete))
+	assert.Equal(t, scraper.calls, 3)
+
+	// A failed fetch isn't an incomplete page
+	scraper = &sequenceScraper{pages: []string{""}}
+	_, err = fetchReadyPage(scraper, draw)
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &incomplete))
+	assert.Equal(t, scraper.calls, 1)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+)
+
+// Replay parses an archived page and prints what getUpdates would change, without writing.
+// The diff is against the slots archived with the page, i.e. what the sync compared it to.
+// Older snapshots without archived slots fall back to the draw's current slots in Pocketbase.
+
+func runReplay(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	drawID := flags.String("draw", "", "draw ID to replay")
+	snapshotID := flags.String("snapshot", "", "snapshot ID, defaults to the latest draw page")
+	list := flags.Bool("list", false, "list archived snapshots for the draw")
+	flags.Parse(args)
+
+	if *drawID == "" {
+		fatal("Usage: replay --draw <id> [--snapshot <id>] [--list]")
+	}
+
+	dir := archiveDir()
+	snapshots, err := listSnapshots(dir, *drawID)
+	if err != nil {
+		fatal("Error reading archive", "error", err)
+	}
+
+	if *list {
+		for _, snapshot := range snapshots {
+			fmt.Println(snapshot.ID, snapshot.FetchedAt.Format("2006-01-02 15:04:05"), snapshot.URL, snapshot.Size)
+		}
+		return
+	}
+
+	token := login()
+	draw, err := getDraw(*drawID, token)
+	if err != nil {
+		fatal("Error getting draw", "error", err)
+	}
+
+	snapshot, ok := findSnapshot(snapshots, *snapshotID, draw.Url)
+	if !ok {
+		fatal("No snapshot found for draw", "draw_id", *drawID)
+	}
+
+	html, err := readSnapshotHTML(dir, snapshot)
+	if err != nil {
+		fatal("Error reading snapshot", "error", err)
+	}
+
+	currentSlots, err := readSnapshotSlots(dir, snapshot)
+	if err != nil {
+		fatal("Error reading snapshot slots", "error", err)
+	}
+	if currentSlots == nil {
+		slog.Warn("Snapshot has no archived slots, comparing against current slots", "snapshot", snapshot.ID)
+		currentSlots = getSlots(draw.ID, token)
+		if currentSlots == nil {
+			fatal("Error getting slots for draw", "draw_id", draw.ID)
+		}
+	}
+
+	scrapedSlots, seeds, _ := parseDraw(html, draw)
+
+	slog.Info("Replaying snapshot", "snapshot", snapshot.ID, "fetched_at", snapshot.FetchedAt, "name", draw.Name, "event", draw.Event, "year", draw.Year)
+	if detected := detectDrawSize(scrapedSlots); detected != draw.Size {
+		fmt.Println(drawSizeDiagnostic(draw, scrapedSlots))
+	}
+	fmt.Printf("Scraped %d slots, expected %d\n", len(scrapedSlots), draw.Size*2-1)
+
+	// Without correction mode's tracker state, corrections and set deletions aren't replayed
+	newSlots, updatedSlots, newSets, updatedSets, _, _ := getUpdates(scrapedSlots, currentSlots, seeds, nil)
+	printReplayDiff(newSlots, updatedSlots, newSets, updatedSets)
+}
+
+// The snapshot with the given ID, or the latest one for the draw URL
+func findSnapshot(snapshots []Snapshot, id string, drawURL string) (Snapshot, bool) {
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		snapshot := snapshots[i]
+		if (id != "" && snapshot.ID == id) || (id == "" && snapshot.URL == drawURL) {
+			return snapshot, true
+		}
+	}
+	return Snapshot{}, false
+}
+
+func printReplayDiff(newSlots SlotSlice, updatedSlots SlotSlice, newSets SetSlice, updatedSets SetSlice) {
+	for _, slot := range newSlots {
+		fmt.Printf("new slot      round %d position %d: %s %s\n", slot.Round, slot.Position, slot.Name, slot.Seed)
+	}
+	for _, slot := range updatedSlots {
+		fmt.Printf("updated slot  %s round %d position %d: %s %s\n", slot.ID, slot.Round, slot.Position, slot.Name, slot.Seed)
+	}
+	for _, set := range newSets {
+		fmt.Printf("new set       slot %s set %d: %d (%d)\n", set.DrawSlotID, set.Number, set.Games, set.Tiebreak)
+	}
+	for _, set := range updatedSets {
+		fmt.Printf("updated set   %s slot %s set %d: %d (%d)\n", set.ID, set.DrawSlotID, set.Number, set.Games, set.Tiebreak)
+	}
+
+	if len(newSlots)+len(updatedSlots)+len(newSets)+len(updatedSets) == 0 {
+		fmt.Println("No changes")
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+)
+
+// Summary of a sync run, printed as a table at the end and written as JSON to
+// RUN_REPORT_PATH when it's set. The run exits non-zero when any draw failed.
+
+const (
+	DrawStatusSynced    = "synced"
+	DrawStatusUnchanged = "unchanged"
+	DrawStatusSkipped   = "skipped"
+	DrawStatusFailed    = "failed"
+)
+
+type DrawReport struct {
+	DrawID        string        `json:"draw_id"`
+	Name          string        `json:"name"`
+	Event         string        `json:"event"`
+	Year          int           `json:"year"`
+	State         string        `json:"state"`
+	Status        string        `json:"status"`
+	Reason        string        `json:"reason,omitempty"`
+	SlotsCreated  int           `json:"slots_created"`
+	SlotsUpdated  int           `json:"slots_updated"`
+	SetsCreated   int           `json:"sets_created"`
+	SetsUpdated   int           `json:"sets_updated"`
+	SetsDeleted   int           `json:"sets_deleted"`
+	Corrections   int           `json:"corrections"`
+	ParseWarnings int           `json:"parse_warnings"`
+	Retries       int           `json:"retries"`
+	Duration      time.Duration `json:"duration_ns"`
+
+	started  time.Time
+	warnings float64
+	failures float64
+}
+
+type RunReport struct {
+	RunID         string        `json:"run_id"`
+	Started       time.Time     `json:"started"`
+	Duration      time.Duration `json:"duration_ns"`
+	Processed     int           `json:"processed"`
+	Skipped       int           `json:"skipped"`
+	Failed        int           `json:"failed"`
+	SlotsCreated  int           `json:"slots_created"`
+	SlotsUpdated  int           `json:"slots_updated"`
+	SetsCreated   int           `json:"sets_created"`
+	SetsUpdated   int           `json:"sets_updated"`
+	SetsDeleted   int           `json:"sets_deleted"`
+	Corrections   int           `json:"corrections"`
+	ParseWarnings int           `json:"parse_warnings"`
+	Retries       int           `json:"retries"`
+	Draws         []*DrawReport `json:"draws"`
+}
+
+func newRunReport(runID string, now time.Time) *RunReport {
+	return &RunReport{RunID: runID, Started: now, Draws: []*DrawReport{}}
+}
+
+// Parse warnings and failed fetch attempts are read from the metrics before and after the draw
+func (rr *RunReport) startDraw(draw DrawRecord) *DrawReport {
+	return &DrawReport{
+		DrawID:   draw.ID,
+		Name:     draw.Name,
+		Event:    draw.Event,
+		Year:     draw.Year,
+		Status:   DrawStatusSynced,
+		started:  time.Now(),
+		warnings: metrics.parseWarnings.total(),
+		failures: metrics.scrapeAttempts.total("", "", "failure"),
+	}
+}
+
+func (rr *RunReport) finishDraw(report *DrawReport, draw DrawRecord, err error) {
+	report.State = draw.State
+	report.Duration = time.Since(report.started)
+	report.ParseWarnings = int(metrics.parseWarnings.total() - report.warnings)
+	report.Retries = int(metrics.scrapeAttempts.total("", "", "failure") - report.failures)
+	if err != nil {
+		report.Status = DrawStatusFailed
+		report.Reason = err.Error()
+	}
+
+	switch report.Status {
+	case DrawStatusFailed:
+		rr.Failed++
+	case DrawStatusSkipped:
+		rr.Skipped++
+	default:
+		rr.Processed++
+	}
+
+	rr.SlotsCreated += report.SlotsCreated
+	rr.SlotsUpdated += report.SlotsUpdated
+	rr.SetsCreated += report.SetsCreated
+	rr.SetsUpdated += report.SetsUpdated
+	rr.SetsDeleted += report.SetsDeleted
+	rr.Corrections += report.Corrections
+	rr.ParseWarnings += report.ParseWarnings
+	rr.Retries += report.Retries
+	rr.Draws = append(rr.Draws, report)
+}
+
+func (rr *RunReport) finish(now time.Time) {
+	rr.Duration = now.Sub(rr.Started)
+}
+
+// Nil when the caller doesn't keep a report, e.g. the daemon
+func (dr *DrawReport) skip(status string, reason string) {
+	if dr == nil {
+		return
+	}
+	dr.Status = status
+	dr.Reason = reason
+}
+
+// Counts of records that were written, failed writes aren't counted
+func (dr *DrawReport) recordWrites(slotsCreated int, slotsUpdated int, setsCreated int, setsUpdated int, setsDeleted int, corrections int) {
+	if dr == nil {
+		return
+	}
+	dr.SlotsCreated = slotsCreated
+	dr.SlotsUpdated = slotsUpdated
+	dr.SetsCreated = setsCreated
+	dr.SetsUpdated = setsUpdated
+	dr.SetsDeleted = setsDeleted
+	dr.Corrections = corrections
+}
+
+func (rr *RunReport) print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DRAW\tSTATUS\tSLOTS +/~\tSETS +/~/-\tCORRECTIONS\tWARNINGS\tRETRIES\tDURATION\tREASON")
+	for _, draw := range rr.Draws {
+		fmt.Fprintf(tw, "%s %s %d\t%s\t%d/%d\t%d/%d/%d\t%d\t%d\t%d\t%s\t%s\n",
+			draw.Name, draw.Event, draw.Year, draw.Status,
+			draw.SlotsCreated, draw.SlotsUpdated,
+			draw.SetsCreated, draw.SetsUpdated, draw.SetsDeleted,
+			draw.Corrections, draw.ParseWarnings, draw.Retries,
+			draw.Duration.Round(time.Millisecond), draw.Reason)
+	}
+	fmt.Fprintf(tw, "TOTAL\t\t%d/%d\t%d/%d/%d\t%d\t%d\t%d\t%s\t\n",
+		rr.SlotsCreated, rr.SlotsUpdated,
+		rr.SetsCreated, rr.SetsUpdated, rr.SetsDeleted,
+		rr.Corrections, rr.ParseWarnings, rr.Retries,
+		rr.Duration.Round(time.Millisecond))
+	tw.Flush()
+	fmt.Fprintf(w, "%d processed, %d skipped, %d failed\n", rr.Processed, rr.Skipped, rr.Failed)
+}
+
+func (rr *RunReport) write(path string) error {
+	data, err := json.MarshalIndent(rr, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func writeRunReport(report *RunReport) {
+	report.print(os.Stdout)
+
+	path := os.Getenv("RUN_REPORT_PATH")
+	if path == "" {
+		return
+	}
+	if err := report.write(path); err != nil {
+		slog.Error("Error writing run report", "path", path, "error", err)
+	}
+}
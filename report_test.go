@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunReport(t *testing.T) {
+	t.Parallel()
+
+	started := time.Date(2025, 1, 20, 14, 0, 0, 0, time.UTC)
+	report := newRunReport("run", started)
+
+	synced := DrawRecord{ID: "a", Name: "Australian Open", Event: "Men's Singles", Year: 2025, State: DrawStateInProgress}
+	drawReport := report.startDraw(synced)
+	drawReport.recordWrites(2, 1, 3, 0, 1, 1)
+	report.finishDraw(drawReport, synced, nil)
+
+	skipped := DrawRecord{ID: "b", Name: "Australian Open", Event: "Women's Singles", Year: 2025, State: DrawStateCompleted}
+	drawReport = report.startDraw(skipped)
+	drawReport.skip(DrawStatusSkipped, "not scraped while completed")
+	report.finishDraw(drawReport, skipped, nil)
+
+	failed := DrawRecord{ID: "c", Name: "Adelaide", Event: "Men's Singles", Year: 2025, State: DrawStateInProgress}
+	drawReport = report.startDraw(failed)
+	report.finishDraw(drawReport, failed, errors.New("incorrect number of scraped slots, expected 31, received 15"))
+
+	report.finish(started.Add(time.Minute))
+
+	assert.Equal(t, report.Processed, 1)
+	assert.Equal(t, report.Skipped, 1)
+	assert.Equal(t, report.Failed, 1)
+	assert.Equal(t, report.SlotsCreated, 2)
+	assert.Equal(t, report.SlotsUpdated, 1)
+	assert.Equal(t, report.SetsCreated, 3)
+	assert.Equal(t, report.SetsDeleted, 1)
+	assert.Equal(t, report.Corrections, 1)
+	assert.Equal(t, report.Duration, time.Minute)
+	assert.Equal(t, report.Draws[2].Status, DrawStatusFailed)
+	assert.Equal(t, report.Draws[2].Reason, "incorrect number of scraped slots, expected 31, received 15")
+
+	var table strings.Builder
+	report.print(&table)
+	assert.Contains(t, table.String(), "Australian Open Men's Singles 2025    synced")
+	assert.Contains(t, table.String(), "1 processed, 1 skipped, 1 failed")
+
+	path := filepath.Join(t.TempDir(), "reports", "run_report.json")
+	assert.NoError(t, report.write(path))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var written RunReport
+	assert.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, written.Failed, 1)
+	assert.Equal(t, len(written.Draws), 3)
+	assert.Equal(t, written.Draws[1].Reason, "not scraped while completed")
+}
+
+func TestDrawReportNil(t *testing.T) {
+	t.Parallel()
+
+	var report *DrawReport
+	report.skip(DrawStatusUnchanged, "page unchanged")
+	report.recordWrites(0, 0, 0, 0, 0, 0)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Rollback replays journal before-images for draw_slot and set_score records, newest first.
+// A record is skipped as a conflict if it no longer matches what the journaled run wrote,
+// e.g. because it was edited manually in the Pocketbase admin UI since.
+
+type RollbackFilter struct {
+	RunID  string
+	DrawID string
+	Since  time.Time
+}
+
+func runRollback(args []string) {
+	flags := flag.NewFlagSet("rollback", flag.ExitOnError)
+	runID := flags.String("run", "", "roll back all changes made by this run ID")
+	drawID := flags.String("draw", "", "roll back changes to this draw, requires --since")
+	since := flags.String("since", "", "roll back changes made at or after this time (RFC3339)")
+	dryRun := flags.Bool("dry-run", false, "preview the rollback without writing to Pocketbase")
+	flags.Parse(args)
+
+	filter := RollbackFilter{RunID: *runID, DrawID: *drawID}
+
+	if *since != "" {
+		sinceTime, err := time.Parse(time.RFC3339, *since)
+		if err != nil {
+			fatal("Invalid --since time, expected RFC3339", "error", err)
+		}
+		filter.Since = sinceTime
+	}
+
+	if filter.RunID == "" && (filter.DrawID == "" || filter.Since.IsZero()) {
+		fatal("Usage: rollback --run <id> | --draw <id> --since <time> [--dry-run]")
+	}
+
+	entries, err := readJournalEntries(journalPath())
+	if err != nil {
+		fatal("Error reading journal", "error", err)
+	}
+
+	selected := selectRollbackEntries(entries, filter)
+	if len(selected) == 0 {
+		slog.Info("No journal entries to roll back")
+		return
+	}
+
+	token := login()
+	journal := newJournal(newRunID(), token)
+	setLogRun(journal.RunID)
+	slog.Info("Rolling back", "changes", len(selected))
+
+	// Dry runs don't write, so track the state each record would be left in
+	simulated := make(map[string]json.RawMessage)
+	rolledBack, conflicts, failed := 0, 0, 0
+
+	for i := len(selected) - 1; i >= 0; i-- {
+		entry := selected[i]
+		recordKey := entry.Collection + "/" + entry.RecordID
+
+		var current map[string]any
+		if state, ok := simulated[recordKey]; ok {
+			current = payloadToMap(state)
+		} else {
+			current, err = getRecord(entry.Collection, entry.RecordID, token)
+			if err != nil {
+				slog.Error("Error getting record for rollback", "error", err)
+				failed++
+				continue
+			}
+		}
+
+		if !matchesPayload(current, entry.After) {
+			slog.Warn("Conflict, skipping", "collection", entry.Collection, "record_id", entry.RecordID, "current", current, "expected", string(entry.After))
+			conflicts++
+			continue
+		}
+
+		if *dryRun {
+			slog.Info("Would restore", "collection", entry.Collection, "record_id", entry.RecordID, "from", string(entry.After), "to", string(entry.Before))
+			simulated[recordKey] = entry.Before
+			rolledBack++
+			continue
+		}
+
+		if err := restoreEntry(entry, current, token, journal); err != nil {
+			slog.Error("Error rolling back", "error", err)
+			failed++
+			continue
+		}
+
+		slog.Info("Restored", "collection", entry.Collection, "record_id", entry.RecordID, "from", string(entry.After), "to", string(entry.Before))
+		rolledBack++
+	}
+
+	slog.Info("Rollback finished", "restored", rolledBack, "conflicts", conflicts, "failed", failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func selectRollbackEntries(entries []JournalEntry, filter RollbackFilter) []JournalEntry {
+	selected := []JournalEntry{}
+
+	for _, entry := range entries {
+		if entry.Collection != "draw_slot" && entry.Collection != "set_score" {
+			continue
+		}
+		if filter.RunID != "" && entry.RunID != filter.RunID {
+			continue
+		}
+		if filter.DrawID != "" && entry.DrawID != filter.DrawID {
+			continue
+		}
+		if !filter.Since.IsZero() {
+			timestamp, err := time.Parse(time.RFC3339, entry.Timestamp)
+			if err != nil || timestamp.Before(filter.Since) {
+				continue
+			}
+		}
+		selected = append(selected, entry)
+	}
+
+	return selected
+}
+
+func payloadToMap(payload json.RawMessage) map[string]any {
+	var result map[string]any
+	if err := json.Unmarshal(payload, &result); err != nil {
+		return nil
+	}
+	return result
+}
+
+// A null payload means the record shouldn't exist
+func matchesPayload(record map[string]any, payload json.RawMessage) bool {
+	expected := payloadToMap(payload)
+	if expected == nil || record == nil {
+		return expected == nil && record == nil
+	}
+
+	for key, value := range expected {
+		if !reflect.DeepEqual(record[key], value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func restoreEntry(entry JournalEntry, current map[string]any, token string, journal *Journal) error {
+	journal.draw = DrawRecord{ID: entry.DrawID, Url: entry.SourceURL}
+
+	// Journal only the fields the script writes, not Pocketbase system fields
+	currentPayload := json.RawMessage("null")
+	if current != nil {
+		fields := make(map[string]any)
+		for key := range payloadToMap(entry.After) {
+			fields[key] = current[key]
+		}
+		currentPayload = toRawJSON(fields)
+	}
+
+	before := payloadToMap(entry.Before)
+
+	switch {
+	case before == nil:
+		if _, err := writeRecord("DELETE", entry.Collection, entry.RecordID, nil, token); err != nil {
+			return err
+		}
+		journal.record("delete", entry.Collection, entry.RecordID, currentPayload, entry.Before)
+	case current == nil:
+		// Recreate with the same ID so other journal entries still refer to it
+		before["id"] = entry.RecordID
+		if _, err := writeRecord("POST", entry.Collection, entry.RecordID, before, token); err != nil {
+			return err
+		}
+		journal.record("create", entry.Collection, entry.RecordID, currentPayload, entry.Before)
+	default:
+		if _, err := writeRecord("PATCH", entry.Collection, entry.RecordID, before, token); err != nil {
+			return err
+		}
+		journal.record("update", entry.Collection, entry.RecordID, currentPayload, entry.Before)
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectRollbackEntries(t *testing.T) {
+	t.Parallel()
+
+	entries := []JournalEntry{
+		{RunID: "run1", DrawID: "draw1", Timestamp: "2025-01-20T10:00:00Z", Collection: "draw_slot", RecordID: "aaa"},
+		{RunID: "run1", DrawID: "draw1", Timestamp: "2025-01-20T10:00:01Z", Collection: "set_score", RecordID: "ss_a_1"},
+		{RunID: "run2", DrawID: "draw1", Timestamp: "2025-01-20T11:00:00Z", Collection: "draw_slot", RecordID: "bbb"},
+		{RunID: "run2", DrawID: "draw2", Timestamp: "2025-01-20T11:00:00Z", Collection: "draw_slot", RecordID: "ddd"},
+		{RunID: "run2", DrawID: "draw1", Timestamp: "2025-01-20T11:00:01Z", Collection: "score", RecordID: "eee"},
+	}
+
+	t.Run("By run", func(t *testing.T) {
+		selected := selectRollbackEntries(entries, RollbackFilter{RunID: "run1"})
+		assert.Equal(t, selected, entries[:2])
+	})
+
+	t.Run("By draw since time", func(t *testing.T) {
+		since, _ := time.Parse(time.RFC3339, "2025-01-20T10:30:00Z")
+		selected := selectRollbackEntries(entries, RollbackFilter{DrawID: "draw1", Since: since})
+		assert.Equal(t, selected, []JournalEntry{entries[2]})
+	})
+}
+
+func TestMatchesPayload(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	record := map[string]any{
+		"id":       "aaa",
+		"draw_id":  "draw1",
+		"round":    float64(2),
+		"position": float64(1),
+		"name":     "Roger Federer",
+		"seed":     "(1)",
+		"updated":  "2025-01-20 10:00:00.000Z",
+	}
+
+	cases := []struct {
+		record   map[string]any
+		payload  string
+		expected bool
+	}{
+		{record, `{"draw_id":"draw1","round":2,"position":1,"name":"Roger Federer","seed":"(1)"}`, true},
+		{record, `{"draw_id":"draw1","round":2,"position":1,"name":"Rafael Nadal","seed":"(2)"}`, false},
+		{record, `null`, false},
+		{nil, `null`, true},
+		{nil, `{"name":"Roger Federer"}`, false},
+	}
+
+	for _, item := range cases {
+		assert.Equal(matchesPayload(item.record, json.RawMessage(item.payload)), item.expected, item.payload)
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Order of play from the ATP daily schedule and WTA order of play pages.
+// Each match is mapped to the round and position it is played in, where the players
+// are in slots position*2-1 and position*2, and saved to the match_schedule collection.
+
+type ScheduledMatch struct {
+	Day        string
+	Court      string
+	Order      int
+	StartTime  string
+	FollowedBy bool
+	Players    [2]string
+}
+
+func scheduleURL(draw DrawRecord) string {
+	switch {
+	case strings.Contains(draw.Url, "atptour.com"):
+		return strings.TrimSuffix(draw.Url, "/draws") + "/daily-schedule"
+	case strings.Contains(draw.Url, "wtatennis.com"):
+		return strings.TrimSuffix(draw.Url, "/draws") + "/order-of-play"
+	default:
+		return ""
+	}
+}
+
+// Start times are either a time like "11:00" or "Followed By" the previous match on the court
+func parseStartTime(s string) (string, bool) {
+	s = trim(strings.Join(strings.Fields(s), " "))
+	lower := strings.ToLower(s)
+
+	if strings.HasPrefix(lower, "followed by") || strings.HasPrefix(lower, "after suitable rest") {
+		return "", true
+	}
+
+	for _, prefix := range []string{"starts at", "not before", "start"} {
+		if strings.HasPrefix(lower, prefix) {
+			return trim(s[len(prefix):]), false
+		}
+	}
+
+	return s, false
+}
+
+func parseATPSchedule(html string) []ScheduledMatch {
+	matches := []ScheduledMatch{}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		slog.Error("Error parsing schedule", "error", err)
+		return matches
+	}
+
+	day := trim(doc.Find(".tournament-day").First().Text())
+
+	doc.Find(".schedule-court").Each(func(_ int, court *goquery.Selection) {
+		courtName := trim(court.Find(".court-name").First().Text())
+
+		court.Find(".schedule").Each(func(i int, match *goquery.Selection) {
+			startTime, followedBy := parseStartTime(match.Find(".schedule-time").Text())
+
+			// Doubles matches list both players of each team
+			names := match.Find(".schedule-players .name a")
+			if names.Length() > 2 {
+				return
+			}
+
+			players := [2]string{}
+			names.Each(func(j int, player *goquery.Selection) {
+				if j < 2 {
+					players[j] = trim(player.Text())
+				}
+			})
+
+			matches = append(matches, ScheduledMatch{
+				Day:        day,
+				Court:      courtName,
+				Order:      i + 1,
+				StartTime:  startTime,
+				FollowedBy: followedBy,
+				Players:    players,
+			})
+		})
+	})
+
+	return matches
+}
+
+func parseWTASchedule(html string) []ScheduledMatch {
+	matches := []ScheduledMatch{}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		slog.Error("Error parsing schedule", "error", err)
+		return matches
+	}
+
+	day := trim(doc.Find(".order-of-play__date").First().Text())
+
+	doc.Find(".court-matches").Each(func(_ int, court *goquery.Selection) {
+		courtName := trim(court.Find(".court-matches__court-name").First().Text())
+
+		court.Find(".match-card").Each(func(i int, match *goquery.Selection) {
+			startTime, followedBy := parseStartTime(match.Find(".match-card__time").Text())
+
+			rows := match.Find(".match-table__row")
+			if rows.Find(".match-table__player-name").Length() > rows.Length() {
+				return
+			}
+
+			players := [2]string{}
+			rows.Each(func(j int, row *goquery.Selection) {
+				if j < 2 {
+					players[j], _ = wtaExtractName(row)
+				}
+			})
+
+			matches = append(matches, ScheduledMatch{
+				Day:        day,
+				Court:      courtName,
+				Order:      i + 1,
+				StartTime:  startTime,
+				FollowedBy: followedBy,
+				Players:    players,
+			})
+		})
+	})
+
+	return matches
+}
+
+// Finds the latest round where both players are in a pair of slots that meet each other
+func matchSlotKey(match ScheduledMatch, slots SlotSlice) (SlotKey, bool) {
+	positions := make(map[string]map[int]int)
+	lastRound := 0
+
+	for _, slot := range slots {
+		if slot.Name == "" {
+			continue
+		}
+		if positions[slot.Name] == nil {
+			positions[slot.Name] = make(map[int]int)
+		}
+		positions[slot.Name][slot.Round] = slot.Position
+		lastRound = max(lastRound, slot.Round)
+	}
+
+	for round := lastRound; round >= 1; round-- {
+		a, okA := positions[match.Players[0]][round]
+		b, okB := positions[match.Players[1]][round]
+		if okA && okB && (a+1)/2 == (b+1)/2 && a != b {
+			return SlotKey{Round: round, Position: (a + 1) / 2}, true
+		}
+	}
+
+	return SlotKey{}, false
+}
+
+func updateSchedule(scraper Scraper, draw DrawRecord, token string, journal *Journal) {
+	if os.Getenv("SYNC_SCHEDULE") != "true" {
+		return
+	}
+
+	url := scheduleURL(draw)
+	if url == "" {
+		return
+	}
+
+	html := scraper.scrape(url)
+	if html == "" {
+		slog.Warn("Skipping schedule, couldn't fetch order of play", "name", draw.Name, "event", draw.Event, "year", draw.Year, "url", url)
+		return
+	}
+
+	var matches []ScheduledMatch
+	if strings.Contains(url, "atptour.com") {
+		matches = parseATPSchedule(html)
+	} else {
+		matches = parseWTASchedule(html)
+	}
+
+	slots := getSlots(draw.ID, token)
+	records := getMatchSchedules(draw.ID, token)
+	if slots == nil || records == nil {
+		slog.Warn("Skipping schedule, couldn't get slots or existing records", "name", draw.Name, "event", draw.Event, "year", draw.Year)
+		return
+	}
+
+	slotIDs := make(map[SlotKey]string)
+	for _, slot := range slots {
+		slotIDs[SlotKey{Round: slot.Round, Position: slot.Position}] = slot.ID
+	}
+
+	existing := make(map[SlotKey]MatchScheduleRecord)
+	for _, record := range records {
+		existing[SlotKey{Round: record.Round, Position: record.Position}] = record
+	}
+
+	scheduled := make(map[SlotKey]bool)
+	for _, match := range matches {
+		key, ok := matchSlotKey(match, slots)
+		if !ok {
+			slog.Warn("Couldn't find scheduled match in draw", "player1", match.Players[0], "player2", match.Players[1])
+			continue
+		}
+		scheduled[key] = true
+
+		requestData := CreateUpdateMatchScheduleReq{
+			DrawID:     draw.ID,
+			Round:      key.Round,
+			Position:   key.Position,
+			Slot1ID:    slotIDs[SlotKey{Round: key.Round, Position: key.Position*2 - 1}],
+			Slot2ID:    slotIDs[SlotKey{Round: key.Round, Position: key.Position * 2}],
+			Day:        match.Day,
+			Court:      match.Court,
+			Order:      match.Order,
+			StartTime:  match.StartTime,
+			FollowedBy: match.FollowedBy,
+		}
+
+		record, ok := existing[key]
+		current := scheduleRequest(record)
+		if ok && current == requestData {
+			continue
+		}
+
+		method, operation, before := "POST", "create", toRawJSON(nil)
+		if ok {
+			method, operation, before = "PATCH", "update", toRawJSON(current)
+		}
+
+		id, err := writeRecord(method, "match_schedule", record.ID, requestData, token)
+		if err != nil {
+			slog.Error("Error saving schedule", "round", key.Round, "position", key.Position, "error", err)
+			continue
+		}
+
+		journal.record(operation, "match_schedule", id, before, toRawJSON(requestData))
+		slog.Info("Saved schedule", "operation", operation, "round", key.Round, "position", key.Position, "court", match.Court, "start_time", match.StartTime)
+	}
+
+	// Matches no longer on the order of play have finished or been moved
+	for key, record := range existing {
+		if scheduled[key] {
+			continue
+		}
+
+		if _, err := writeRecord("DELETE", "match_schedule", record.ID, nil, token); err != nil {
+			slog.Error("Error clearing schedule", "round", key.Round, "position", key.Position, "error", err)
+			continue
+		}
+
+		journal.record("delete", "match_schedule", record.ID, toRawJSON(scheduleRequest(record)), toRawJSON(nil))
+		slog.Info("Cleared schedule", "round", key.Round, "position", key.Position)
+	}
+}
+
+func scheduleRequest(record MatchScheduleRecord) CreateUpdateMatchScheduleReq {
+	return CreateUpdateMatchScheduleReq{
+		DrawID:     record.DrawID,
+		Round:      record.Round,
+		Position:   record.Position,
+		Slot1ID:    record.Slot1ID,
+		Slot2ID:    record.Slot2ID,
+		Day:        record.Day,
+		Court:      record.Court,
+		Order:      record.Order,
+		StartTime:  record.StartTime,
+		FollowedBy: record.FollowedBy,
+	}
+}
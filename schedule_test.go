@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const atpScheduleHTML = `
+<div class="tournament-day">Monday, 20 January</div>
+<div class="schedule-court">
+	<div class="court-name">Rod Laver Arena</div>
+	<div class="schedule">
+		<div class="schedule-time">Starts At 11:00</div>
+		<div class="schedule-players"><div class="name"><a>Roger Federer</a></div><div class="name"><a>Andy Murray</a></div></div>
+	</div>
+	<div class="schedule">
+		<div class="schedule-time">Followed By</div>
+		<div class="schedule-players"><div class="name"><a>Roger Federer</a></div><div class="name"><a>Novak Djokovic</a></div></div>
+	</div>
+</div>`
+
+const wtaScheduleHTML = `
+<div class="order-of-play__date">Monday 20 January</div>
+<div class="court-matches">
+	<div class="court-matches__court-name">Margaret Court Arena</div>
+	<div class="match-card">
+		<div class="match-card__time">Not Before 19:00</div>
+		<div class="match-table__row"><div class="match-table__player-name"><span class="match-table__player-fullname">Iga Swiatek</span></div></div>
+		<div class="match-table__row"><div class="match-table__player-name"><span class="match-table__player-fullname">Coco Gauff</span></div></div>
+	</div>
+</div>`
+
+func TestParseSchedule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ATP", func(t *testing.T) {
+		matches := parseATPSchedule(atpScheduleHTML)
+		assert.Equal(t, matches, []ScheduledMatch{
+			{Day: "Monday, 20 January", Court: "Rod Laver Arena", Order: 1, StartTime: "11:00", Players: [2]string{"Roger Federer", "Andy Murray"}},
+			{Day: "Monday, 20 January", Court: "Rod Laver Arena", Order: 2, FollowedBy: true, Players: [2]string{"Roger Federer", "Novak Djokovic"}},
+		})
+	})
+
+	t.Run("ATP doubles skipped", func(t *testing.T) {
+		html := strings.Replace(atpScheduleHTML, `</div>
+</div>`, `</div>
+	<div class="schedule">
+		<div class="schedule-time">Followed By</div>
+		<div class="schedule-players">
+			<div class="name"><a>Rohan Bopanna</a></div><div class="name"><a>Matthew Ebden</a></div>
+			<div class="name"><a>Rajeev Ram</a></div><div class="name"><a>Joe Salisbury</a></div>
+		</div>
+	</div>
+</div>`, 1)
+		assert.Len(t, parseATPSchedule(html), 2)
+	})
+
+	t.Run("WTA", func(t *testing.T) {
+		matches := parseWTASchedule(wtaScheduleHTML)
+		assert.Equal(t, matches, []ScheduledMatch{
+			{Day: "Monday 20 January", Court: "Margaret Court Arena", Order: 1, StartTime: "19:00", Players: [2]string{"Iga Swiatek", "Coco Gauff"}},
+		})
+	})
+}
+
+func TestMatchSlotKey(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	slots := SlotSlice{
+		Slot{Round: 1, Position: 1, Name: "Roger Federer"},
+		Slot{Round: 1, Position: 2, Name: "Andy Murray"},
+		Slot{Round: 1, Position: 3, Name: "Rafael Nadal"},
+		Slot{Round: 1, Position: 4, Name: "Novak Djokovic"},
+		Slot{Round: 2, Position: 1, Name: "Roger Federer"},
+		Slot{Round: 2, Position: 2, Name: "Novak Djokovic"},
+		Slot{Round: 3, Position: 1, Name: ""},
+	}
+
+	key, ok := matchSlotKey(ScheduledMatch{Players: [2]string{"Novak Djokovic", "Roger Federer"}}, slots)
+	assert.True(ok)
+	assert.Equal(key, SlotKey{Round: 2, Position: 1})
+
+	key, ok = matchSlotKey(ScheduledMatch{Players: [2]string{"Rafael Nadal", "Novak Djokovic"}}, slots)
+	assert.True(ok)
+	assert.Equal(key, SlotKey{Round: 1, Position: 2})
+
+	_, ok = matchSlotKey(ScheduledMatch{Players: [2]string{"Roger Federer", "Rafael Nadal"}}, slots)
+	assert.False(ok)
+}
+
+func TestScheduleURL(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal(scheduleURL(DrawRecord{Url: "https://www.atptour.com/en/scores/current/australian-open/580/draws"}),
+		"https://www.atptour.com/en/scores/current/australian-open/580/daily-schedule")
+	assert.Equal(scheduleURL(DrawRecord{Url: "https://www.wtatennis.com/tournaments/901/australian-open/2025/draws"}),
+		"https://www.wtatennis.com/tournaments/901/australian-open/2025/order-of-play")
+	assert.Equal(scheduleURL(DrawRecord{Url: "https://example.com/draws"}), "")
+}
+
+func TestUpdateScheduleClearsFinishedMatches(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch r.URL.Path {
+		case "/api/collections/slots_with_scores/records":
+			json.NewEncoder(w).Encode(SlotRes{Items: []SlotRecord{
+				{ID: "s1", DrawID: "draw1", Round: 1, Position: 1, Name: "Roger Federer"},
+				{ID: "s2", DrawID: "draw1", Round: 1, Position: 2, Name: "Andy Murray"},
+				{ID: "s3", DrawID: "draw1", Round: 1, Position: 3, Name: "Rafael Nadal"},
+				{ID: "s4", DrawID: "draw1", Round: 1, Position: 4, Name: "Novak Djokovic"},
+			}})
+		case "/api/collections/match_schedule/records":
+			if r.Method == "GET" {
+				json.NewEncoder(w).Encode(MatchScheduleRes{Items: []MatchScheduleRecord{
+					{ID: "m2", DrawID: "draw1", Round: 1, Position: 2, Slot1ID: "s3", Slot2ID: "s4", Court: "Court 3", Order: 1},
+				}})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"id": "m1"})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("BASE_URL", server.URL)
+	t.Setenv("SYNC_SCHEDULE", "true")
+
+	draw := DrawRecord{ID: "draw1", Url: "https://www.atptour.com/en/scores/current/australian-open/580/draws"}
+	updateSchedule(&staticScraper{html: atpScheduleHTML}, draw, "token", nil)
+
+	// Federer and Murray are scheduled, Nadal and Djokovic have finished
+	assert.Equal(t, requests, []string{
+		"GET /api/collections/slots_with_scores/records",
+		"GET /api/collections/match_schedule/records",
+		"POST /api/collections/match_schedule/records",
+		"DELETE /api/collections/match_schedule/records/m2",
+	})
+}
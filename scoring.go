@@ -0,0 +1,255 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Users earn points for each prediction matching the name in a draw slot.
+// Round 1 is the draw itself, so predictions start in round 2.
+
+type ScoringConfig struct {
+	// Points for a correct pick by round, rounds past the end use the last weight
+	RoundWeights map[int]int
+	// Extra points when the picked player beat a better seed
+	UpsetBonus int
+}
+
+type UserScore struct {
+	UserID          string
+	Points          int
+	Correct         int
+	ChampionCorrect bool
+	CorrectByRound  map[int]int
+	// Earliest prediction created time, used as a leaderboard tie-breaker
+	FirstSubmitted string
+}
+
+func loadScoringConfig() ScoringConfig {
+	config := ScoringConfig{
+		RoundWeights: map[int]int{2: 1, 3: 2, 4: 4, 5: 8, 6: 16, 7: 32, 8: 64},
+		UpsetBonus:   1,
+	}
+
+	// Comma separated weights starting from round 2, e.g. "1,2,4,8,16,32,64"
+	if weights := os.Getenv("SCORING_ROUND_WEIGHTS"); weights != "" {
+		config.RoundWeights = make(map[int]int)
+		for i, weight := range strings.Split(weights, ",") {
+			points, err := strconv.Atoi(trim(weight))
+			if err != nil {
+				slog.Error("Error parsing SCORING_ROUND_WEIGHTS", "error", err)
+				continue
+			}
+			config.RoundWeights[i+2] = points
+		}
+	}
+
+	if bonus := os.Getenv("SCORING_UPSET_BONUS"); bonus != "" {
+		points, err := strconv.Atoi(bonus)
+		if err != nil {
+			slog.Error("Error parsing SCORING_UPSET_BONUS", "error", err)
+		} else {
+			config.UpsetBonus = points
+		}
+	}
+
+	return config
+}
+
+func (c ScoringConfig) weight(round int) int {
+	if weight, ok := c.RoundWeights[round]; ok {
+		return weight
+	}
+
+	last := 0
+	for r := range c.RoundWeights {
+		if r < round && r > last {
+			last = r
+		}
+	}
+	return c.RoundWeights[last]
+}
+
+// Unseeded players, wildcards and qualifiers rank below every seed
+func seedNumber(seed string) int {
+	number, err := strconv.Atoi(strings.Trim(seed, "() "))
+	if err != nil || number <= 0 {
+		return 1 << 30
+	}
+	return number
+}
+
+// Whether the player in the slot beat a better seed to get there
+func isUpset(slots map[SlotKey]Slot, key SlotKey) bool {
+	winner, ok := slots[key]
+	if !ok || winner.Name == "" || key.Round < 2 {
+		return false
+	}
+
+	for _, position := range []int{key.Position*2 - 1, key.Position * 2} {
+		feeder, ok := slots[SlotKey{Round: key.Round - 1, Position: position}]
+		if !ok || feeder.Name == "" || feeder.Name == winner.Name {
+			continue
+		}
+		return seedNumber(winner.Seed) > seedNumber(feeder.Seed)
+	}
+
+	return false
+}
+
+func computeScores(slots SlotSlice, predictions []PredictionRecord, config ScoringConfig) []UserScore {
+	slotMap := make(map[SlotKey]Slot)
+	championRound := 0
+	for _, slot := range slots {
+		slotMap[SlotKey{Round: slot.Round, Position: slot.Position}] = slot
+		championRound = max(championRound, slot.Round)
+	}
+
+	scores := make(map[string]*UserScore)
+	for _, prediction := range predictions {
+		score, ok := scores[prediction.UserID]
+		if !ok {
+			score = &UserScore{UserID: prediction.UserID, CorrectByRound: make(map[int]int)}
+			scores[prediction.UserID] = score
+		}
+
+		if score.FirstSubmitted == "" || (prediction.Created != "" && prediction.Created < score.FirstSubmitted) {
+			score.FirstSubmitted = prediction.Created
+		}
+
+		if prediction.Round < 2 || prediction.Name == "" {
+			continue
+		}
+
+		key := SlotKey{Round: prediction.Round, Position: prediction.Position}
+		slot, ok := slotMap[key]
+		if !ok || slot.Name != prediction.Name {
+			continue
+		}
+
+		score.Correct++
+		score.CorrectByRound[prediction.Round]++
+		score.ChampionCorrect = score.ChampionCorrect || prediction.Round == championRound
+		score.Points += config.weight(prediction.Round)
+		if isUpset(slotMap, key) {
+			score.Points += config.UpsetBonus
+		}
+	}
+
+	result := []UserScore{}
+	for _, score := range scores {
+		result = append(result, *score)
+	}
+
+	// Sort for consistent order for testing/debugging
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].UserID < result[j].UserID
+	})
+
+	return result
+}
+
+// Recomputes every user's total for the draw from scratch, so it is safe to run repeatedly.
+// Returns whether any score changed.
+func updateScores(draw DrawRecord, token string, journal *Journal) bool {
+	slots := getSlots(draw.ID, token)
+	predictions := getPredictions(draw.ID, token)
+	if slots == nil || predictions == nil {
+		slog.Warn("Skipping scoring, couldn't get slots or predictions", "name", draw.Name, "event", draw.Event, "year", draw.Year)
+		return false
+	}
+	predictions = filterPredictionsBeforeClose(predictions, draw)
+
+	records := getScores(draw.ID, token)
+	if records == nil {
+		slog.Warn("Skipping scoring, couldn't get scores", "name", draw.Name, "event", draw.Event, "year", draw.Year)
+		return false
+	}
+
+	existing := make(map[string]ScoreRecord)
+	for _, record := range records {
+		existing[record.UserID] = record
+	}
+
+	changed := false
+	scored := make(map[string]bool)
+
+	for _, score := range computeScores(slots, predictions, loadScoringConfig()) {
+		scored[score.UserID] = true
+
+		correctByRound := make(map[string]int)
+		for round, correct := range score.CorrectByRound {
+			correctByRound[strconv.Itoa(round)] = correct
+		}
+
+		requestData := CreateUpdateScoreReq{
+			DrawID:          draw.ID,
+			UserID:          score.UserID,
+			Points:          score.Points,
+			Correct:         score.Correct,
+			ChampionCorrect: score.ChampionCorrect,
+			CorrectByRound:  correctByRound,
+			FirstSubmitted:  score.FirstSubmitted,
+		}
+
+		record, ok := existing[score.UserID]
+		current := scoreRequest(record)
+		if ok && reflect.DeepEqual(current, requestData) {
+			continue
+		}
+
+		before := toRawJSON(nil)
+		method, operation := "POST", "create"
+		if ok {
+			before = toRawJSON(current)
+			method, operation = "PATCH", "update"
+		}
+
+		id, err := writeRecord(method, "score", record.ID, requestData, token)
+		if err != nil {
+			slog.Error("Error saving score", "user_id", score.UserID, "error", err)
+			continue
+		}
+
+		journal.record(operation, "score", id, before, toRawJSON(requestData))
+		slog.Info("Saved score", "operation", operation, "user_id", score.UserID, "points", score.Points)
+		changed = true
+	}
+
+	// Users whose predictions were all deleted or made after prediction_close no longer score
+	for _, record := range records {
+		if scored[record.UserID] {
+			continue
+		}
+
+		if _, err := writeRecord("DELETE", "score", record.ID, nil, token); err != nil {
+			slog.Error("Error deleting score", "user_id", record.UserID, "error", err)
+			continue
+		}
+
+		journal.record("delete", "score", record.ID, toRawJSON(scoreRequest(record)), toRawJSON(nil))
+		slog.Info("Deleted score", "user_id", record.UserID, "points", record.Points)
+		changed = true
+	}
+
+	return changed
+}
+
+func scoreRequest(record ScoreRecord) CreateUpdateScoreReq {
+	if record.CorrectByRound == nil {
+		record.CorrectByRound = make(map[string]int)
+	}
+	return CreateUpdateScoreReq{
+		DrawID:          record.DrawID,
+		UserID:          record.UserID,
+		Points:          record.Points,
+		Correct:         record.Correct,
+		ChampionCorrect: record.ChampionCorrect,
+		CorrectByRound:  record.CorrectByRound,
+		FirstSubmitted:  record.FirstSubmitted,
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Four player draw, Nadal beats Federer in the final
+var scoredSlots = SlotSlice{
+	Slot{Round: 1, Position: 1, Name: "Roger Federer", Seed: "(1)"},
+	Slot{Round: 1, Position: 2, Name: "Andy Murray", Seed: ""},
+	Slot{Round: 1, Position: 3, Name: "Rafael Nadal", Seed: "(2)"},
+	Slot{Round: 1, Position: 4, Name: "Novak Djokovic", Seed: "(WC)"},
+	Slot{Round: 2, Position: 1, Name: "Roger Federer", Seed: "(1)"},
+	Slot{Round: 2, Position: 2, Name: "Novak Djokovic", Seed: "(WC)"},
+	Slot{Round: 3, Position: 1, Name: "", Seed: ""},
+}
+
+var scoringConfig = ScoringConfig{
+	RoundWeights: map[int]int{2: 1, 3: 2},
+	UpsetBonus:   3,
+}
+
+func TestComputeScores(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Points by round with upset bonus", func(t *testing.T) {
+		predictions := []PredictionRecord{
+			{UserID: "user1", Round: 2, Position: 1, Name: "Roger Federer"},
+			{UserID: "user1", Round: 2, Position: 2, Name: "Rafael Nadal"},
+			{UserID: "user2", Round: 2, Position: 1, Name: "Roger Federer"},
+			{UserID: "user2", Round: 2, Position: 2, Name: "Novak Djokovic"},
+			{UserID: "user3", Round: 2, Position: 1, Name: "Andy Murray"},
+			{UserID: "user3", Round: 3, Position: 1, Name: "Andy Murray"},
+		}
+
+		scores := computeScores(scoredSlots, predictions, scoringConfig)
+		assert.Equal(t, scores, []UserScore{
+			{UserID: "user1", Points: 1, Correct: 1, CorrectByRound: map[int]int{2: 1}},
+			{UserID: "user2", Points: 5, Correct: 2, CorrectByRound: map[int]int{2: 2}},
+			{UserID: "user3", Points: 0, Correct: 0, CorrectByRound: map[int]int{}},
+		})
+	})
+
+	t.Run("Recomputed when slots change", func(t *testing.T) {
+		predictions := []PredictionRecord{
+			{UserID: "user1", Round: 3, Position: 1, Name: "Novak Djokovic"},
+		}
+
+		scores := computeScores(scoredSlots, predictions, scoringConfig)
+		assert.Equal(t, scores, []UserScore{{UserID: "user1", Points: 0, Correct: 0, CorrectByRound: map[int]int{}}})
+
+		final := append(SlotSlice{}, scoredSlots...)
+		final[6] = Slot{Round: 3, Position: 1, Name: "Novak Djokovic", Seed: "(WC)"}
+
+		scores = computeScores(final, predictions, scoringConfig)
+		assert.Equal(t, scores, []UserScore{{UserID: "user1", Points: 5, Correct: 1, ChampionCorrect: true, CorrectByRound: map[int]int{3: 1}}})
+		assert.Equal(t, computeScores(final, predictions, scoringConfig), scores)
+	})
+
+	t.Run("Earliest submission", func(t *testing.T) {
+		predictions := []PredictionRecord{
+			{UserID: "user1", Round: 2, Position: 1, Name: "Roger Federer", Created: "2025-01-11 09:00:00.000Z"},
+			{UserID: "user1", Round: 2, Position: 2, Name: "Rafael Nadal", Created: "2025-01-10 18:30:00.000Z"},
+		}
+
+		scores := computeScores(scoredSlots, predictions, scoringConfig)
+		assert.Equal(t, scores[0].FirstSubmitted, "2025-01-10 18:30:00.000Z")
+	})
+
+	t.Run("No predictions", func(t *testing.T) {
+		scores := computeScores(scoredSlots, []PredictionRecord{}, scoringConfig)
+		assert.Equal(t, scores, []UserScore{})
+	})
+}
+
+func TestScoringWeight(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	config := ScoringConfig{RoundWeights: map[int]int{2: 1, 3: 2, 4: 4}}
+	assert.Equal(config.weight(2), 1)
+	assert.Equal(config.weight(4), 4)
+	assert.Equal(config.weight(6), 4)
+}
+
+func TestSeedNumber(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal(seedNumber("(1)"), 1)
+	assert.Equal(seedNumber("(32)"), 32)
+	assert.Greater(seedNumber(""), 32)
+	assert.Greater(seedNumber("(WC)"), 32)
+	assert.Greater(seedNumber("(Q)"), 32)
+}
+
+func TestUpdateScoresDeletesStaleScores(t *testing.T) {
+	var mu sync.Mutex
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/slots_with_scores/records"):
+			json.NewEncoder(w).Encode(SlotRes{Items: []SlotRecord{
+				{ID: "a", DrawID: "draw1", Round: 1, Position: 1, Name: "Roger Federer"},
+				{ID: "b", DrawID: "draw1", Round: 1, Position: 2, Name: "Andy Murray"},
+				{ID: "c", DrawID: "draw1", Round: 2, Position: 1, Name: "Roger Federer"},
+			}})
+		case strings.HasSuffix(r.URL.Path, "/prediction/records"):
+			json.NewEncoder(w).Encode(PredictionRes{TotalPages: 1, Items: []PredictionRecord{
+				{ID: "p1", UserID: "u1", DrawID: "draw1", Round: 2, Position: 1, Name: "Roger Federer", Created: "2025-01-01 00:00:00.000Z", Updated: "2025-01-01 00:00:00.000Z"},
+			}})
+		case strings.HasSuffix(r.URL.Path, "/score/records") && r.Method == "GET":
+			json.NewEncoder(w).Encode(ScoreRes{TotalPages: 1, Items: []ScoreRecord{
+				{ID: "s1", DrawID: "draw1", UserID: "u1", Points: 1, Correct: 1, ChampionCorrect: true, CorrectByRound: map[string]int{"2": 1}, FirstSubmitted: "2025-01-01 00:00:00.000Z"},
+				{ID: "s2", DrawID: "draw1", UserID: "u2", Points: 1, Correct: 1},
+			}})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("BASE_URL", server.URL)
+
+	// u2's predictions were all deleted, so their score is removed
+	draw := DrawRecord{ID: "draw1", Prediction_Close: "2025-01-02 12:00:00.000Z", Timezone: "UTC"}
+	assert.True(t, updateScores(draw, "token", nil))
+	assert.Contains(t, requests, "DELETE /api/collections/score/records/s2")
+	assert.NotContains(t, requests, "PATCH /api/collections/score/records/s1")
+}
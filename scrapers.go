@@ -1,12 +1,8 @@
 package main
 
 import (
-	"crypto/tls"
 	"fmt"
-	"io"
-	"log"
-	"net/http"
-	"net/url"
+	"log/slog"
 	"os"
 	"sort"
 	"strconv"
@@ -20,79 +16,92 @@ type Scraper interface {
 	scrape(targetURL string) string
 }
 
-type RealScraper struct{}
+type RealScraper struct {
+	last ResponseMeta
+	// Pages served by each backend, keyed by provider/backend
+	Served map[string]int
+}
+
+// Details of the last successful response, saved with archived pages
+type ResponseMeta struct {
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type"`
+	Attempts    int    `json:"attempts"`
+	Backend     string `json:"backend"`
+}
+
+func (r *RealScraper) lastResponse() ResponseMeta {
+	return r.last
+}
 
+// Tries each backend in the provider's fallback chain until one returns a rendered page
 func (r *RealScraper) scrape(targetURL string) string {
-	printWithTimestamp("Visiting:", targetURL)
+	slog.Info("Visiting", "url", targetURL)
 
-	proxyURL, err := url.Parse(os.Getenv("PROXY_URL"))
+	backends, err := fetchBackendsFor(targetURL)
 	if err != nil {
-		log.Println(fmt.Sprintf("Error parsing proxy URL - %s:", targetURL), err)
+		slog.Error("Error configuring fetch backends", "url", targetURL, "error", err)
 		return ""
 	}
 
-	client := &http.Client{
-		Transport: &http.Transport{
-			Proxy:           http.ProxyURL(proxyURL),
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
-		// Waiting for rendering can take a while so we set a longer timeout
-		Timeout: 600 * time.Second,
+	maxRetries, err := strconv.Atoi(os.Getenv("FETCH_RETRIES"))
+	if err != nil || maxRetries <= 0 {
+		maxRetries = 5
 	}
 
-	req, err := http.NewRequest("GET", targetURL, nil)
-	if err != nil {
-		log.Println("Error creating request:", err)
-		return ""
-	}
+	for _, backend := range backends {
+		// Exponential backoff retry mechanism
+		backoff := time.Second
 
-	// Bright Data header to wait for is-winner class to appear
-	// Used for WTA draws to indicate that scores and winners have been rendered
-	if strings.Contains(targetURL, "wtatennis.com") {
-		req.Header.Set("x-unblock-expect", "{\"element\": \".match-table__tie-break\"}")
-	}
+		for i := range maxRetries {
+			slog.Debug("Fetching", "url", targetURL, "attempt", i+1, "backend", backend.name())
+			start := time.Now()
+			html, meta, err := backend.fetch(targetURL)
+			metrics.scrapeDuration.observe(time.Since(start).Seconds(), providerFor(targetURL), backend.name())
 
-	// Exponential backoff retry mechanism
-	maxRetries := 5
-	backoff := time.Second
-
-	for i := range maxRetries {
-		printWithTimestamp("Attempt:", i+1)
-		resp, err := client.Do(req)
-		if err != nil || resp.StatusCode != 200 {
-			log.Println(fmt.Sprintf("Error making request - %s:", targetURL), err, "Status Code:", resp.Status, "Response Body:", resp.Body)
-			if i < maxRetries-1 {
-				time.Sleep(backoff)
-				backoff *= 2
+			if err == nil && !looksRendered(targetURL, html) {
+				err = fmt.Errorf("page doesn't look rendered")
+			}
+			if err != nil {
+				metrics.scrapeAttempts.inc(providerFor(targetURL), backend.name(), "failure")
+				slog.Warn("Error making request", "url", targetURL, "attempt", i+1, "backend", backend.name(), "status", meta.StatusCode, "error", err)
+				if i < maxRetries-1 {
+					time.Sleep(backoff)
+					backoff *= 2
+				}
 				continue
 			}
-			return ""
-		}
-		defer resp.Body.Close()
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Println(fmt.Sprintf("Error reading response body - %s:", targetURL), err)
-			return ""
-		}
+			metrics.scrapeAttempts.inc(providerFor(targetURL), backend.name(), "success")
+			meta.Attempts = i + 1
+			meta.Backend = backend.name()
+			r.last = meta
+			if r.Served == nil {
+				r.Served = make(map[string]int)
+			}
+			r.Served[providerFor(targetURL)+"/"+backend.name()]++
 
-		printWithTimestamp("Finished scraping:", targetURL)
-		return string(body)
+			slog.Info("Finished scraping", "url", targetURL, "attempt", i+1, "backend", backend.name(), "status", meta.StatusCode)
+			return html
+		}
 	}
 
 	return ""
 }
 
 func scrapeATP(scraper Scraper, draw DrawRecord) (SlotSlice, map[string]string) {
+	return parseATP(scraper.scrape(draw.Url), draw)
+}
+
+func parseATP(html string, draw DrawRecord) (SlotSlice, map[string]string) {
 	slots := SlotSlice{}
 	seeds := make(map[string]string)
 
-	html := scraper.scrape(draw.Url)
 	reader := strings.NewReader(html)
 
 	doc, err := goquery.NewDocumentFromReader(reader)
 	if err != nil {
-		log.Println(err)
+		slog.Error("Error parsing draw", "error", err)
 	}
 
 	roundContainers := doc.Find(".draw-content").FilterFunction(func(_ int, selection *goquery.Selection) bool {
@@ -111,7 +120,8 @@ func scrapeATP(scraper Scraper, draw DrawRecord) (SlotSlice, map[string]string)
 			seed := trim(player.Find("span").Text())
 
 			sets := SetSlice{}
-			rawSets := rawSlot.Find(".score-item")
+			// The current set of a live match is parsed by parseATPLive
+			rawSets := rawSlot.Find(".score-item").Not(".live")
 			rawSets.EachWithBreak(func(i int, set *goquery.Selection) bool {
 				scores := set.Find("span").Map(func(_ int, span *goquery.Selection) string {
 					return trim(span.Text())
@@ -128,7 +138,8 @@ func scrapeATP(scraper Scraper, draw DrawRecord) (SlotSlice, map[string]string)
 
 				games, err := strconv.Atoi(gamesStr)
 				if err != nil {
-					log.Println("ATP - Error converting games to int:", err)
+					slog.Error("ATP - Error converting games to int", "error", err)
+					metrics.parseWarnings.inc("atp")
 				}
 
 				tiebreakStr := ""
@@ -140,7 +151,8 @@ func scrapeATP(scraper Scraper, draw DrawRecord) (SlotSlice, map[string]string)
 				if tiebreakStr != "" {
 					tiebreak, err = strconv.Atoi(tiebreakStr)
 					if err != nil {
-						log.Println("ATP - Error converting tiebreak to int:", err)
+						slog.Error("ATP - Error converting tiebreak to int", "error", err)
+						metrics.parseWarnings.inc("atp")
 					}
 				}
 
@@ -166,15 +178,18 @@ func scrapeATP(scraper Scraper, draw DrawRecord) (SlotSlice, map[string]string)
 }
 
 func scrapeWTA(scraper Scraper, draw DrawRecord) (SlotSlice, map[string]string) {
+	return parseWTA(scraper.scrape(draw.Url), draw)
+}
+
+func parseWTA(html string, draw DrawRecord) (SlotSlice, map[string]string) {
 	slots := SlotSlice{}
 	seeds := make(map[string]string)
 
-	html := scraper.scrape(draw.Url)
 	reader := strings.NewReader(html)
 
 	doc, err := goquery.NewDocumentFromReader(reader)
 	if err != nil {
-		log.Println(err)
+		slog.Error("Error parsing draw", "error", err)
 	}
 
 	slotMap := make(map[SlotKey]*Slot)
@@ -190,7 +205,8 @@ func scrapeWTA(scraper Scraper, draw DrawRecord) (SlotSlice, map[string]string)
 			seeds[name] = seed
 
 			sets := SetSlice{}
-			rawSets := rawSlot.Find(".match-table__score-cell")
+			// The current set of a live match is parsed by parseWTALive
+			rawSets := rawSlot.Find(".match-table__score-cell").Not(".is-live")
 			rawSets.EachWithBreak(func(i int, set *goquery.Selection) bool {
 				scores := strings.Fields(set.Text())
 
@@ -205,7 +221,8 @@ func scrapeWTA(scraper Scraper, draw DrawRecord) (SlotSlice, map[string]string)
 
 				games, err := strconv.Atoi(gameStr)
 				if err != nil {
-					log.Println("WTA - Error converting games to int:", err)
+					slog.Error("WTA - Error converting games to int", "error", err)
+					metrics.parseWarnings.inc("wta")
 				}
 
 				tiebreakStr := ""
@@ -217,7 +234,8 @@ func scrapeWTA(scraper Scraper, draw DrawRecord) (SlotSlice, map[string]string)
 				if tiebreakStr != "" {
 					tiebreak, err = strconv.Atoi(tiebreakStr)
 					if err != nil {
-						log.Println("WTA - Error converting tiebreak to int:", err)
+						slog.Error("WTA - Error converting tiebreak to int", "error", err)
+						metrics.parseWarnings.inc("wta")
 					}
 				}
 
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// HTTP server for the daemon, listening on METRICS_ADDR (default :9090)
+func startServer(health *HealthState) *http.Server {
+	addr := os.Getenv("METRICS_ADDR")
+	if addr == "" {
+		addr = ":9090"
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsHandler(metricsRegistry))
+	mux.HandleFunc("/healthz", health.healthz)
+	mux.HandleFunc("/readyz", health.readyz)
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Error running server", "addr", addr, "error", err)
+		}
+	}()
+
+	return server
+}
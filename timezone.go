@@ -0,0 +1,93 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+	_ "time/tzdata"
+)
+
+// Draw dates are calendar dates at the tournament, so they are evaluated in the draw's IANA
+// timezone. The timezone field on the draw record wins, otherwise it's looked up from the
+// tournament name, otherwise UTC.
+
+var tournamentTimezones = []struct {
+	Match string
+	Zone  string
+}{
+	{"australian open", "Australia/Melbourne"},
+	{"adelaide", "Australia/Adelaide"},
+	{"brisbane", "Australia/Brisbane"},
+	{"auckland", "Pacific/Auckland"},
+	{"doha", "Asia/Qatar"},
+	{"qatar", "Asia/Qatar"},
+	{"dubai", "Asia/Dubai"},
+	{"indian wells", "America/Los_Angeles"},
+	{"miami", "America/New_York"},
+	{"monte carlo", "Europe/Monaco"},
+	{"madrid", "Europe/Madrid"},
+	{"rome", "Europe/Rome"},
+	{"internazionali", "Europe/Rome"},
+	{"roland garros", "Europe/Paris"},
+	{"french open", "Europe/Paris"},
+	{"wimbledon", "Europe/London"},
+	{"canadian open", "America/Toronto"},
+	{"toronto", "America/Toronto"},
+	{"montreal", "America/Toronto"},
+	{"cincinnati", "America/New_York"},
+	{"us open", "America/New_York"},
+	{"beijing", "Asia/Shanghai"},
+	{"china open", "Asia/Shanghai"},
+	{"shanghai", "Asia/Shanghai"},
+	{"wuhan", "Asia/Shanghai"},
+	{"tokyo", "Asia/Tokyo"},
+	{"paris", "Europe/Paris"},
+	{"riyadh", "Asia/Riyadh"},
+	{"turin", "Europe/Rome"},
+}
+
+func tournamentTimezone(name string) string {
+	name = strings.ToLower(name)
+	for _, tz := range tournamentTimezones {
+		if strings.Contains(name, tz.Match) {
+			return tz.Zone
+		}
+	}
+	return ""
+}
+
+func drawLocation(draw DrawRecord) *time.Location {
+	zone := draw.Timezone
+	if zone == "" {
+		zone = tournamentTimezone(draw.Name)
+	}
+	if zone == "" {
+		return time.UTC
+	}
+
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		slog.Warn("Invalid timezone", "name", draw.Name, "event", draw.Event, "year", draw.Year, "error", err)
+		return time.UTC
+	}
+	return location
+}
+
+// Midnight at the start of a stored date in the draw's timezone
+func drawDate(s string, location *time.Location) (time.Time, error) {
+	t, err := parsePocketbaseTime(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location), nil
+}
+
+// A date-only value like "2025-01-12" is midnight in the draw's timezone.
+// A full timestamp is an exact time, including midnight UTC.
+func drawTime(s string, location *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if _, err := time.Parse("2006-01-02", s); err == nil {
+		return drawDate(s, location)
+	}
+	return parsePocketbaseTime(s)
+}
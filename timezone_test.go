@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrawLocation(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, drawLocation(DrawRecord{Name: "Australian Open"}).String(), "Australia/Melbourne")
+	assert.Equal(t, drawLocation(DrawRecord{Name: "BNP Paribas Open - Indian Wells"}).String(), "America/Los_Angeles")
+	assert.Equal(t, drawLocation(DrawRecord{Name: "Australian Open", Timezone: "UTC"}).String(), "UTC")
+	assert.Equal(t, drawLocation(DrawRecord{Name: "Unknown"}), time.UTC)
+	assert.Equal(t, drawLocation(DrawRecord{Name: "Unknown", Timezone: "Not/AZone"}), time.UTC)
+}
+
+func TestDrawTimezoneEdges(t *testing.T) {
+	t.Parallel()
+
+	// Melbourne is UTC+11 in January, the draw finishes before the UTC date changes
+	melbourne := DrawRecord{
+		Name:             "Australian Open",
+		Start_Date:       "2025-01-12 00:00:00.000Z",
+		End_Date:         "2025-01-26 00:00:00.000Z",
+		Prediction_Close: "2025-01-12",
+		Size:             4,
+	}
+
+	t.Run("Melbourne archive", func(t *testing.T) {
+		lastNight := time.Date(2025, 1, 26, 12, 59, 0, 0, time.UTC)
+		nextMorning := time.Date(2025, 1, 26, 13, 0, 0, 0, time.UTC)
+		assert.NotEqual(t, computeDrawState(melbourne, nil, lastNight), DrawStateArchived)
+		assert.Equal(t, computeDrawState(melbourne, nil, nextMorning), DrawStateArchived)
+	})
+
+	t.Run("Melbourne prediction close", func(t *testing.T) {
+		assert.False(t, predictionsClosed(melbourne, time.Date(2025, 1, 11, 12, 59, 0, 0, time.UTC)))
+		assert.True(t, predictionsClosed(melbourne, time.Date(2025, 1, 11, 13, 0, 0, 0, time.UTC)))
+
+		// A full timestamp at midnight UTC is exact, not shifted to Melbourne
+		exact := melbourne
+		exact.Prediction_Close = "2025-01-12 00:00:00.000Z"
+		assert.False(t, predictionsClosed(exact, time.Date(2025, 1, 11, 23, 59, 0, 0, time.UTC)))
+		assert.True(t, predictionsClosed(exact, time.Date(2025, 1, 12, 0, 0, 0, 0, time.UTC)))
+	})
+
+	// Indian Wells is UTC-7 in March, the final is played after the UTC date changes
+	indianWells := DrawRecord{
+		Name:       "BNP Paribas Open - Indian Wells",
+		Start_Date: "2025-03-05 00:00:00.000Z",
+		End_Date:   "2025-03-16 00:00:00.000Z",
+		Size:       4,
+		State:      DrawStateScheduled,
+	}
+
+	t.Run("Indian Wells archive", func(t *testing.T) {
+		final := time.Date(2025, 3, 17, 3, 0, 0, 0, time.UTC)
+		dayAfter := time.Date(2025, 3, 17, 7, 0, 0, 0, time.UTC)
+		assert.NotEqual(t, computeDrawState(indianWells, nil, final), DrawStateArchived)
+		assert.Equal(t, computeDrawState(indianWells, nil, dayAfter), DrawStateArchived)
+	})
+
+	t.Run("Indian Wells scrape window", func(t *testing.T) {
+		assert.False(t, shouldScrape(indianWells, time.Date(2025, 3, 2, 7, 59, 0, 0, time.UTC)))
+		assert.True(t, shouldScrape(indianWells, time.Date(2025, 3, 2, 8, 0, 0, 0, time.UTC)))
+	})
+
+	t.Run("Match hours", func(t *testing.T) {
+		inProgress := melbourne
+		inProgress.State = DrawStateInProgress
+		// 02:00 UTC is 13:00 in Melbourne
+		assert.Equal(t, pollInterval(inProgress, time.Date(2025, 1, 20, 2, 0, 0, 0, time.UTC)), 5*time.Minute)
+		// 16:00 UTC is 03:00 in Melbourne
+		assert.Equal(t, pollInterval(inProgress, time.Date(2025, 1, 20, 16, 0, 0, 0, time.UTC)), time.Hour)
+	})
+}
@@ -10,6 +10,7 @@ type Slot struct {
 	Name     string
 	Seed     string
 	Sets     SetSlice
+	Locked   bool
 }
 
 type Set struct {
@@ -18,6 +19,7 @@ type Set struct {
 	Number     int
 	Games      int
 	Tiebreak   int
+	Locked     bool
 }
 
 type SlotSlice []Slot
@@ -37,6 +39,23 @@ type SlotKey struct {
 	Position int
 }
 
+// Scraped value that disagrees with a slot or set locked by an admin
+type LockConflict struct {
+	Collection string
+	RecordID   string
+	Round      int
+	Position   int
+	Field      string
+	Locked     string
+	Scraped    string
+}
+
+type LockConflictSlice []LockConflict
+
+func (lc *LockConflictSlice) add(c LockConflict) {
+	*lc = append(*lc, c)
+}
+
 // Pocketbase API types
 
 type UserRecord struct {
@@ -55,15 +74,18 @@ type UserAuthRes struct {
 }
 
 type DrawRecord struct {
-	ID               string `json:"id"`
-	Name             string `json:"name"`
-	Event            string `json:"event"`
-	Year             int    `json:"year"`
-	Url              string `json:"url"`
-	Start_Date       string `json:"start_date"`
-	End_Date         string `json:"end_date"`
-	Prediction_Close string `json:"prediction_close"`
-	Size             int    `json:"size"`
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	Event              string `json:"event"`
+	Year               int    `json:"year"`
+	Url                string `json:"url"`
+	Start_Date         string `json:"start_date"`
+	End_Date           string `json:"end_date"`
+	Prediction_Close   string `json:"prediction_close"`
+	Size               int    `json:"size"`
+	Predictions_Locked bool   `json:"predictions_locked"`
+	State              string `json:"state"`
+	Timezone           string `json:"timezone"`
 }
 
 type DrawRes struct {
@@ -81,21 +103,27 @@ type SlotRecord struct {
 	Position     int    `json:"position"`
 	Name         string `json:"name"`
 	Seed         string `json:"seed"`
+	Locked       bool   `json:"locked"`
 	Set1ID       string `json:"set1_id"`
 	Set1Games    *int   `json:"set1_games"`
 	Set1Tiebreak *int   `json:"set1_tiebreak"`
+	Set1Locked   bool   `json:"set1_locked"`
 	Set2ID       string `json:"set2_id"`
 	Set2Games    *int   `json:"set2_games"`
 	Set2Tiebreak *int   `json:"set2_tiebreak"`
+	Set2Locked   bool   `json:"set2_locked"`
 	Set3ID       string `json:"set3_id"`
 	Set3Games    *int   `json:"set3_games"`
 	Set3Tiebreak *int   `json:"set3_tiebreak"`
+	Set3Locked   bool   `json:"set3_locked"`
 	Set4ID       string `json:"set4_id"`
 	Set4Games    *int   `json:"set4_games"`
 	Set4Tiebreak *int   `json:"set4_tiebreak"`
+	Set4Locked   bool   `json:"set4_locked"`
 	Set5ID       string `json:"set5_id"`
 	Set5Games    *int   `json:"set5_games"`
 	Set5Tiebreak *int   `json:"set5_tiebreak"`
+	Set5Locked   bool   `json:"set5_locked"`
 }
 
 type SlotRes struct {
@@ -120,3 +148,173 @@ type CreateUpdateSetReq struct {
 	Games      int    `json:"games"`
 	Tiebreak   int    `json:"tiebreak"`
 }
+
+type PredictionRecord struct {
+	ID       string `json:"id"`
+	UserID   string `json:"user_id"`
+	DrawID   string `json:"draw_id"`
+	Round    int    `json:"round"`
+	Position int    `json:"position"`
+	Name     string `json:"name"`
+	Seed     string `json:"seed"`
+	Created  string `json:"created"`
+	Updated  string `json:"updated"`
+}
+
+type PredictionRes struct {
+	Page       int                `json:"page"`
+	PerPage    int                `json:"perPage"`
+	TotalPages int                `json:"totalPages"`
+	TotalItems int                `json:"totalItems"`
+	Items      []PredictionRecord `json:"items"`
+}
+
+type ScoreRecord struct {
+	ID              string         `json:"id"`
+	DrawID          string         `json:"draw_id"`
+	UserID          string         `json:"user_id"`
+	Points          int            `json:"points"`
+	Correct         int            `json:"correct"`
+	ChampionCorrect bool           `json:"champion_correct"`
+	CorrectByRound  map[string]int `json:"correct_by_round"`
+	FirstSubmitted  string         `json:"first_submitted"`
+}
+
+type ScoreRes struct {
+	Page       int           `json:"page"`
+	PerPage    int           `json:"perPage"`
+	TotalPages int           `json:"totalPages"`
+	TotalItems int           `json:"totalItems"`
+	Items      []ScoreRecord `json:"items"`
+}
+
+type CreateUpdateScoreReq struct {
+	DrawID          string         `json:"draw_id"`
+	UserID          string         `json:"user_id"`
+	Points          int            `json:"points"`
+	Correct         int            `json:"correct"`
+	ChampionCorrect bool           `json:"champion_correct"`
+	CorrectByRound  map[string]int `json:"correct_by_round"`
+	FirstSubmitted  string         `json:"first_submitted"`
+}
+
+type LeaderboardRecord struct {
+	ID           string `json:"id"`
+	Scope        string `json:"scope"`
+	DrawID       string `json:"draw_id"`
+	Year         int    `json:"year"`
+	UserID       string `json:"user_id"`
+	Rank         int    `json:"rank"`
+	PreviousRank int    `json:"previous_rank"`
+	Movement     int    `json:"movement"`
+	Points       int    `json:"points"`
+}
+
+type LeaderboardRes struct {
+	Page       int                 `json:"page"`
+	PerPage    int                 `json:"perPage"`
+	TotalPages int                 `json:"totalPages"`
+	TotalItems int                 `json:"totalItems"`
+	Items      []LeaderboardRecord `json:"items"`
+}
+
+type CreateUpdateLeaderboardReq struct {
+	Scope        string `json:"scope"`
+	DrawID       string `json:"draw_id"`
+	Year         int    `json:"year"`
+	UserID       string `json:"user_id"`
+	Rank         int    `json:"rank"`
+	PreviousRank int    `json:"previous_rank"`
+	Movement     int    `json:"movement"`
+	Points       int    `json:"points"`
+}
+
+type PredictionSnapshotReq struct {
+	DrawID      string             `json:"draw_id"`
+	Hash        string             `json:"hash"`
+	Count       int                `json:"count"`
+	Predictions []PredictionRecord `json:"predictions"`
+	// Picks created or edited after prediction_close, kept for review but not scored
+	LateCount int                `json:"late_count"`
+	Late      []PredictionRecord `json:"late_predictions"`
+	TakenAt   string             `json:"taken_at"`
+}
+
+type CreateDrawReq struct {
+	Name             string `json:"name"`
+	Event            string `json:"event"`
+	Year             int    `json:"year"`
+	Url              string `json:"url"`
+	Start_Date       string `json:"start_date"`
+	End_Date         string `json:"end_date"`
+	Prediction_Close string `json:"prediction_close"`
+	Size             int    `json:"size"`
+	State            string `json:"state"`
+	Timezone         string `json:"timezone"`
+}
+
+type MatchScheduleRecord struct {
+	ID         string `json:"id"`
+	DrawID     string `json:"draw_id"`
+	Round      int    `json:"round"`
+	Position   int    `json:"position"`
+	Slot1ID    string `json:"slot1_id"`
+	Slot2ID    string `json:"slot2_id"`
+	Day        string `json:"day"`
+	Court      string `json:"court"`
+	Order      int    `json:"order"`
+	StartTime  string `json:"start_time"`
+	FollowedBy bool   `json:"followed_by"`
+}
+
+type MatchScheduleRes struct {
+	Page       int                   `json:"page"`
+	PerPage    int                   `json:"perPage"`
+	TotalPages int                   `json:"totalPages"`
+	TotalItems int                   `json:"totalItems"`
+	Items      []MatchScheduleRecord `json:"items"`
+}
+
+type CreateUpdateMatchScheduleReq struct {
+	DrawID     string `json:"draw_id"`
+	Round      int    `json:"round"`
+	Position   int    `json:"position"`
+	Slot1ID    string `json:"slot1_id"`
+	Slot2ID    string `json:"slot2_id"`
+	Day        string `json:"day"`
+	Court      string `json:"court"`
+	Order      int    `json:"order"`
+	StartTime  string `json:"start_time"`
+	FollowedBy bool   `json:"followed_by"`
+}
+
+type LiveScoreRecord struct {
+	ID         string `json:"id"`
+	DrawID     string `json:"draw_id"`
+	DrawSlotID string `json:"draw_slot_id"`
+	Round      int    `json:"round"`
+	Position   int    `json:"position"`
+	SetNumber  int    `json:"set_number"`
+	Games      int    `json:"games"`
+	Points     string `json:"points"`
+	Serving    bool   `json:"serving"`
+}
+
+type LiveScoreRes struct {
+	Page       int               `json:"page"`
+	PerPage    int               `json:"perPage"`
+	TotalPages int               `json:"totalPages"`
+	TotalItems int               `json:"totalItems"`
+	Items      []LiveScoreRecord `json:"items"`
+}
+
+type CreateUpdateLiveScoreReq struct {
+	DrawID     string `json:"draw_id"`
+	DrawSlotID string `json:"draw_slot_id"`
+	Round      int    `json:"round"`
+	Position   int    `json:"position"`
+	SetNumber  int    `json:"set_number"`
+	Games      int    `json:"games"`
+	Points     string `json:"points"`
+	Serving    bool   `json:"serving"`
+}